@@ -1,45 +1,102 @@
 package coincap
 
 import (
-	"encoding/json"
+	"context"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
 // AssetsRequest contains the paramaters for modifying a query to
 // the "/assets" endpoint. Search can be a symbol (BTC) or an asset id (bitcoin)
 type AssetsRequest struct {
-	Search string `json:"search,omitempty"` // search by asset id (bitcoin) or symbol (BTC)
-	Limit  int    `json:"limit,omitempty"`  // limit number of returned results (Max: 2000)
-	Offset int    `json:"offset,omitempty"` // skip the first N entries of the result set
+	Search string   `json:"search,omitempty"` // search by asset id (bitcoin) or symbol (BTC)
+	IDs    []string `json:"ids,omitempty"`    // only return the assets with these ids (bitcoin, ethereum)
+	Limit  int      `json:"limit,omitempty"`  // limit number of returned results (Max: 2000)
+	Offset int      `json:"offset,omitempty"` // skip the first N entries of the result set
 }
 
+// maxIDsPerRequest bounds the number of ids sent in a single request by AssetsByIDs
+const maxIDsPerRequest = 100
+
 // Asset contains various information about a given CoinCap asset such as Bitcoin
 type Asset struct {
-	ID                string `json:"id"`                // unique identifier for asset
-	Rank              string `json:"rank"`              // rank in terms of the asset's market cap
-	Symbol            string `json:"symbol"`            // common symbol to identify the asset
-	Name              string `json:"name"`              // proper name for asset
-	Supply            string `json:"supply"`            // available supply for trading
-	MaxSupply         string `json:"maxSupply"`         // total quantity of asset issued
-	MarketCapUsd      string `json:"marketCapUsd"`      // supply x price
-	VolumeUsd24Hr     string `json:"volumeUsd24Hr"`     // quantity of trading volume in USD over last 24 hours
-	PriceUsd          string `json:"priceUsd"`          // volume weighted price of the asset in USD
-	ChangePercent24Hr string `json:"changePercent24Hr"` // percent change in value in the last 24 hours
-	Vwap24Hr          string `json:"vwap24Hr"`          // Volume Weighted Average Price in the last 24 hours
+	ID                string     `json:"id"`                // unique identifier for asset
+	Rank              string     `json:"rank"`              // rank in terms of the asset's market cap
+	Symbol            string     `json:"symbol"`            // common symbol to identify the asset
+	Name              string     `json:"name"`              // proper name for asset
+	Supply            string     `json:"supply"`            // available supply for trading
+	MaxSupply         NullString `json:"maxSupply"`         // total quantity of asset issued, null if there is no maximum
+	MarketCapUsd      string     `json:"marketCapUsd"`      // supply x price
+	VolumeUsd24Hr     string     `json:"volumeUsd24Hr"`     // quantity of trading volume in USD over last 24 hours
+	PriceUsd          string     `json:"priceUsd"`          // volume weighted price of the asset in USD
+	ChangePercent24Hr NullString `json:"changePercent24Hr"` // percent change in value in the last 24 hours
+	Vwap24Hr          NullString `json:"vwap24Hr"`          // Volume Weighted Average Price in the last 24 hours
+	Explorer          NullString `json:"explorer"`          // url of a block explorer for the asset
+}
+
+// RankDecimal parses Rank as an exact Decimal
+func (a *Asset) RankDecimal() (Decimal, error) {
+	return parseDecimalField("rank", a.Rank)
+}
+
+// SupplyDecimal parses Supply as an exact Decimal
+func (a *Asset) SupplyDecimal() (Decimal, error) {
+	return parseDecimalField("supply", a.Supply)
+}
+
+// MaxSupplyDecimal parses MaxSupply as an exact Decimal.
+// The result is null for assets without a maximum supply
+func (a *Asset) MaxSupplyDecimal() (Decimal, error) {
+	return parseDecimalField("maxSupply", a.MaxSupply.String)
+}
+
+// MarketCapUsdDecimal parses MarketCapUsd as an exact Decimal
+func (a *Asset) MarketCapUsdDecimal() (Decimal, error) {
+	return parseDecimalField("marketCapUsd", a.MarketCapUsd)
+}
+
+// VolumeUsd24HrDecimal parses VolumeUsd24Hr as an exact Decimal
+func (a *Asset) VolumeUsd24HrDecimal() (Decimal, error) {
+	return parseDecimalField("volumeUsd24Hr", a.VolumeUsd24Hr)
+}
+
+// PriceUsdDecimal parses PriceUsd as an exact Decimal
+func (a *Asset) PriceUsdDecimal() (Decimal, error) {
+	return parseDecimalField("priceUsd", a.PriceUsd)
+}
+
+// ChangePercent24HrDecimal parses ChangePercent24Hr as an exact Decimal
+func (a *Asset) ChangePercent24HrDecimal() (Decimal, error) {
+	return parseDecimalField("changePercent24Hr", a.ChangePercent24Hr.String)
+}
+
+// Vwap24HrDecimal parses Vwap24Hr as an exact Decimal
+func (a *Asset) Vwap24HrDecimal() (Decimal, error) {
+	return parseDecimalField("vwap24Hr", a.Vwap24Hr.String)
 }
 
 // Assets returns a list of CoinCap Asset entries filtered by the request's
 // search criteria and a timestamp
 func (c *Client) Assets(reqParams *AssetsRequest) ([]*Asset, *Timestamp, error) {
+	return c.AssetsWithContext(context.Background(), reqParams)
+}
+
+// AssetsWithContext is like Assets but carries ctx through the request,
+// allowing the caller to set deadlines or cancel the call
+func (c *Client) AssetsWithContext(ctx context.Context, reqParams *AssetsRequest) ([]*Asset, *Timestamp, error) {
 
 	// Prepare the query and encode optional parameters
-	req, err := http.NewRequest("GET", c.baseURL+"/assets", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/assets", nil)
 	if err != nil {
 		return nil, nil, err
 	}
 	params := req.URL.Query()
 	params.Add("search", reqParams.Search)
+	if len(reqParams.IDs) > 0 {
+		params.Add("ids", strings.Join(reqParams.IDs, ","))
+	}
 	if reqParams.Limit > 0 {
 		params.Add("limit", strconv.Itoa(reqParams.Limit))
 	}
@@ -49,35 +106,113 @@ func (c *Client) Assets(reqParams *AssetsRequest) ([]*Asset, *Timestamp, error)
 	req.URL.RawQuery = params.Encode()
 
 	// make the request
-	ccResp, err := c.fetchAndParse(req)
+	ccResp, err := c.fetchAndParse("/assets", req)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	// Unmarshal the deferred json from the data field
 	var assets []*Asset
-	json.Unmarshal(*ccResp.Data, &assets)
+	if err := decodeData("/assets", ccResp, &assets); err != nil {
+		return nil, nil, err
+	}
 
 	return assets, ccResp.Timestamp, nil
 }
 
+// AssetsByIDs returns the assets with the given ids in the order they were requested,
+// along with the ids that CoinCap does not know about. Long lists are split across
+// multiple requests and duplicate ids are only returned once. The returned timestamp
+// is the oldest of the responses
+func (c *Client) AssetsByIDs(ids []string) ([]*Asset, []string, *Timestamp, error) {
+	return c.AssetsByIDsWithContext(context.Background(), ids)
+}
+
+// AssetsByIDsWithContext is like AssetsByIDs but carries ctx through the requests,
+// allowing the caller to set deadlines or cancel the call. A ResponseMeta requested
+// through ctx counts the attempts of every request and describes the last response
+func (c *Client) AssetsByIDsWithContext(ctx context.Context, ids []string) ([]*Asset, []string, *Timestamp, error) {
+
+	// drop duplicates while keeping the requested order
+	seen := make(map[string]bool, len(ids))
+	unique := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	// fetch the ids in chunks that fit into a single request
+	found := make(map[string]*Asset, len(unique))
+	var timestamp *Timestamp
+	meta := c.combineMeta(ctx)
+	for start := 0; start < len(unique); start += maxIDsPerRequest {
+		end := start + maxIDsPerRequest
+		if end > len(unique) {
+			end = len(unique)
+		}
+		chunk := unique[start:end]
+
+		var (
+			assets []*Asset
+			ts     *Timestamp
+		)
+		err := meta.request(ctx, func(ctx context.Context) error {
+			var err error
+			assets, ts, err = c.AssetsWithContext(ctx, &AssetsRequest{IDs: chunk, Limit: len(chunk)})
+			return err
+		})
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		for _, asset := range assets {
+			found[asset.ID] = asset
+		}
+		if timestamp == nil || ts.Before(timestamp.Time) {
+			timestamp = ts
+		}
+	}
+
+	// merge the results in the requested order
+	assets := make([]*Asset, 0, len(found))
+	var missing []string
+	for _, id := range unique {
+		if asset, ok := found[id]; ok {
+			assets = append(assets, asset)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+
+	return assets, missing, timestamp, nil
+}
+
 // AssetByID requests an asset by its CoinCap ID
 func (c *Client) AssetByID(id string) (*Asset, *Timestamp, error) {
+	return c.AssetByIDWithContext(context.Background(), id)
+}
 
-	req, err := http.NewRequest("GET", c.baseURL+"/assets/"+id, nil)
+// AssetByIDWithContext is like AssetByID but carries ctx through the request,
+// allowing the caller to set deadlines or cancel the call
+func (c *Client) AssetByIDWithContext(ctx context.Context, id string) (*Asset, *Timestamp, error) {
+
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/assets/"+id, nil)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	// make the request
-	ccResp, err := c.fetchAndParse(req)
+	ccResp, err := c.fetchAndParse("/assets/{id}", req)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	// Unmarshal the deferred json from the data field
 	asset := new(Asset)
-	json.Unmarshal(*ccResp.Data, &asset)
+	if err := decodeData("/assets/{id}", ccResp, &asset); err != nil {
+		return nil, nil, err
+	}
 
 	return asset, ccResp.Timestamp, nil
 }
@@ -98,17 +233,31 @@ type AssetHistory struct {
 	Time     Timestamp `json:"time"`     // Timestamp correlating to the given price
 }
 
+// PriceUSDDecimal parses PriceUSD as an exact Decimal
+func (h *AssetHistory) PriceUSDDecimal() (Decimal, error) {
+	return parseDecimalField("priceUsd", h.PriceUSD)
+}
+
 // AssetHistoryByID returns USD price history of a given asset.
 // If no interval is specified 1 hour (h1) is chosen as the default.
 func (c *Client) AssetHistoryByID(id string, reqParams *AssetHistoryRequest) ([]*AssetHistory, *Timestamp, error) {
+	return c.AssetHistoryByIDWithContext(context.Background(), id, reqParams)
+}
+
+// AssetHistoryByIDWithContext is like AssetHistoryByID but carries ctx through the request,
+// allowing the caller to set deadlines or cancel the call
+func (c *Client) AssetHistoryByIDWithContext(ctx context.Context, id string, reqParams *AssetHistoryRequest) ([]*AssetHistory, *Timestamp, error) {
 
 	// Default interval to an hour if none was provided
 	if reqParams.Interval == "" {
 		reqParams.Interval = Hour
 	}
+	if !reqParams.Interval.ValidForAssetHistory() {
+		return nil, nil, fmt.Errorf("Interval %q is not supported for asset history", reqParams.Interval)
+	}
 
 	// Prepare the query
-	req, err := http.NewRequest("GET", c.baseURL+"/assets/"+id+"/history", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/assets/"+id+"/history", nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -116,10 +265,10 @@ func (c *Client) AssetHistoryByID(id string, reqParams *AssetHistoryRequest) ([]
 	// encode optional parameters
 	params := req.URL.Query()
 	params.Add("interval", string(reqParams.Interval))
-	if reqParams.Start != nil {
+	if reqParams.Start != nil && !reqParams.Start.IsZero() {
 		params.Add("start", reqParams.Start.String())
 	}
-	if reqParams.End != nil {
+	if reqParams.End != nil && !reqParams.End.IsZero() {
 		params.Add("end", reqParams.End.String())
 	}
 	if reqParams.Limit > 0 {
@@ -131,14 +280,93 @@ func (c *Client) AssetHistoryByID(id string, reqParams *AssetHistoryRequest) ([]
 	req.URL.RawQuery = params.Encode()
 
 	// make the request
-	ccResp, err := c.fetchAndParse(req)
+	ccResp, err := c.fetchAndParse("/assets/{id}/history", req)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	// Unmarshal the deferred json from the data field
 	var history []*AssetHistory
-	json.Unmarshal(*ccResp.Data, &history)
+	if err := decodeData("/assets/{id}/history", ccResp, &history); err != nil {
+		return nil, nil, err
+	}
 
 	return history, ccResp.Timestamp, nil
 }
+
+// AssetMarketsRequest contains the paramaters for modifying a query to
+// the "/assets/{{id}}/markets" endpoint.
+type AssetMarketsRequest struct {
+	Limit  int `json:"limit,omitempty"`  // limit number of returned results (Max: 2000)
+	Offset int `json:"offset,omitempty"` // skip the first N entries of the result set
+}
+
+// AssetMarket contains information about a market where a given asset is traded
+type AssetMarket struct {
+	ExchangeID    string     `json:"exchangeId"`    // unique identifier for exchange
+	BaseID        string     `json:"baseId"`        // unique identifier for the asset purchased
+	QuoteID       string     `json:"quoteId"`       // unique identifier for the asset used to purchase base
+	BaseSymbol    string     `json:"baseSymbol"`    // most common symbol used to identify the base asset
+	QuoteSymbol   string     `json:"quoteSymbol"`   // most common symbol used to identify the quote asset
+	VolumeUsd24Hr NullString `json:"volumeUsd24Hr"` // volume transacted in this market in the last 24 hours
+	PriceUsd      NullString `json:"priceUsd"`      // the amount of quote asset traded for one unit of base asset in USD
+	VolumePercent NullString `json:"volumePercent"` // percent of the asset's total volume transacted in this market
+}
+
+// VolumeUsd24HrDecimal parses VolumeUsd24Hr as an exact Decimal
+func (m *AssetMarket) VolumeUsd24HrDecimal() (Decimal, error) {
+	return parseDecimalField("volumeUsd24Hr", m.VolumeUsd24Hr.String)
+}
+
+// PriceUsdDecimal parses PriceUsd as an exact Decimal
+func (m *AssetMarket) PriceUsdDecimal() (Decimal, error) {
+	return parseDecimalField("priceUsd", m.PriceUsd.String)
+}
+
+// VolumePercentDecimal parses VolumePercent as an exact Decimal
+func (m *AssetMarket) VolumePercentDecimal() (Decimal, error) {
+	return parseDecimalField("volumePercent", m.VolumePercent.String)
+}
+
+// AssetMarketsByID returns all the markets where the asset with the given ID is traded.
+// GET /assets/{{id}}/markets
+func (c *Client) AssetMarketsByID(id string, reqParams *AssetMarketsRequest) ([]*AssetMarket, *Timestamp, error) {
+	return c.AssetMarketsByIDWithContext(context.Background(), id, reqParams)
+}
+
+// AssetMarketsByIDWithContext is like AssetMarketsByID but carries ctx through the request,
+// allowing the caller to set deadlines or cancel the call
+func (c *Client) AssetMarketsByIDWithContext(ctx context.Context, id string, reqParams *AssetMarketsRequest) ([]*AssetMarket, *Timestamp, error) {
+
+	// Prepare the query
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/assets/"+id+"/markets", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// encode optional parameters
+	if reqParams != nil {
+		params := req.URL.Query()
+		if reqParams.Limit > 0 {
+			params.Add("limit", strconv.Itoa(reqParams.Limit))
+		}
+		if reqParams.Offset > 0 {
+			params.Add("offset", strconv.Itoa(reqParams.Offset))
+		}
+		req.URL.RawQuery = params.Encode()
+	}
+
+	// make the request
+	ccResp, err := c.fetchAndParse("/assets/{id}/markets", req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Unmarshal the deferred json from the data field
+	var markets []*AssetMarket
+	if err := decodeData("/assets/{id}/markets", ccResp, &markets); err != nil {
+		return nil, nil, err
+	}
+
+	return markets, ccResp.Timestamp, nil
+}
@@ -1,9 +1,12 @@
 package coincap
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -80,3 +83,223 @@ func TestASsetHistoryLive(t *testing.T) {
 		log.Fatal(err)
 	}
 }
+
+func TestAssetsMalformed(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+	serveFixture("/assets", "assets_malformed.json")
+
+	assets, _, err := client.Assets(&AssetsRequest{})
+	if assets != nil {
+		t.Errorf("Expected no assets to be returned but got %+v", assets)
+	}
+	assertDecodeError(t, err, "/assets")
+}
+
+func TestAssetByID(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+	serveFixture("/assets/{id}", "assetByID.json")
+
+	asset, _, err := client.AssetByID("ethereum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if asset.ID != "ethereum" {
+		t.Errorf("Expected asset ID to be ethereum but was %s", asset.ID)
+	}
+}
+
+func TestAssetByIDMalformed(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+	serveFixture("/assets/{id}", "assetByID_malformed.json")
+
+	_, _, err := client.AssetByID("ethereum")
+	assertDecodeError(t, err, "/assets/{id}")
+}
+
+func TestAssetHistoryByID(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+	serveFixture("/assets/{id}/history", "assetHistory.json")
+
+	history, _, err := client.AssetHistoryByID("bitcoin", &AssetHistoryRequest{Interval: Day})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("Expected 3 history entries but got %d", len(history))
+	}
+	if history[0].PriceUSD != "6379.3997635993342453" {
+		t.Errorf("Unexpected price %s", history[0].PriceUSD)
+	}
+}
+
+func TestAssetHistoryByIDMalformed(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+	serveFixture("/assets/{id}/history", "assetHistory_malformed.json")
+
+	_, _, err := client.AssetHistoryByID("bitcoin", &AssetHistoryRequest{Interval: Day})
+	assertDecodeError(t, err, "/assets/{id}/history")
+}
+
+func TestAssetDecimals(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+	serveFixture("/assets/{id}", "assetByID.json")
+
+	asset, _, err := client.AssetByID("ethereum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	price, err := asset.PriceUsdDecimal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price.String() != "227.2919917637434706" {
+		t.Errorf("Unexpected price %s", price)
+	}
+	maxSupply, err := asset.MaxSupplyDecimal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !maxSupply.IsNull() {
+		t.Errorf("Expected null max supply but got %s", maxSupply)
+	}
+
+	asset.Supply = "lots"
+	if _, err := asset.SupplyDecimal(); err == nil {
+		t.Errorf("Expected an error for a non numeric supply")
+	}
+}
+
+func TestAssetMarketsByID(t *testing.T) {
+
+	teardown := setup()
+	defer teardown()
+
+	r.HandleFunc("/assets/{id}/markets", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("limit") != "3" || r.URL.Query().Get("offset") != "1" {
+			t.Errorf("Expected limit and offset to be sent but query was %s", r.URL.RawQuery)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, fixture("assetMarkets.json"))
+	})
+
+	params := &AssetMarketsRequest{
+		Limit:  3,
+		Offset: 1,
+	}
+
+	markets, _, err := client.AssetMarketsByID("bitcoin", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(markets) != 3 {
+		t.Fatalf("Expected 3 markets but got %d", len(markets))
+	}
+	got := markets[0]
+	expected := AssetMarket{
+		ExchangeID:    "Binance",
+		BaseID:        "bitcoin",
+		QuoteID:       "tether",
+		BaseSymbol:    "BTC",
+		QuoteSymbol:   "USDT",
+		VolumeUsd24Hr: NewNullString("277775213.1923032624064566"),
+		PriceUsd:      NewNullString("6263.8645034633024446"),
+		VolumePercent: NewNullString("7.4239157877678087"),
+	}
+	if *got != expected {
+		t.Errorf("Expected %+v, Got %+v", expected, *got)
+	}
+	if !markets[2].VolumeUsd24Hr.IsNull() {
+		t.Errorf("Expected null volume to be kept as null")
+	}
+}
+
+func TestAssetMarketsByIDMalformed(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+	serveFixture("/assets/{id}/markets", "assetMarkets_malformed.json")
+
+	_, _, err := client.AssetMarketsByID("bitcoin", nil)
+	assertDecodeError(t, err, "/assets/{id}/markets")
+}
+
+func TestAssetMarketsByIDLive(t *testing.T) {
+	// hit the actual API
+	client := NewClient(nil)
+	markets, _, err := client.AssetMarketsByID("bitcoin", &AssetMarketsRequest{Limit: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(markets) == 0 {
+		t.Errorf("No markets were returned")
+	}
+}
+
+func TestAssetsByIDs(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	// every id is known except the ones starting with "unknown"
+	requests := 0
+	r.HandleFunc("/assets", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		ids := strings.Split(r.URL.Query().Get("ids"), ",")
+		if len(ids) > maxIDsPerRequest {
+			t.Errorf("Expected at most %d ids per request but got %d", maxIDsPerRequest, len(ids))
+		}
+		if r.URL.Query().Get("limit") != strconv.Itoa(len(ids)) {
+			t.Errorf("Expected limit to match the number of ids but was %s", r.URL.Query().Get("limit"))
+		}
+
+		// respond in reverse order to make sure the client restores the requested order
+		var data []string
+		for i := len(ids) - 1; i >= 0; i-- {
+			if !strings.HasPrefix(ids[i], "unknown") {
+				data = append(data, fmt.Sprintf(`{"id":%q,"rank":"1"}`, ids[i]))
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `{"data":[%s],"timestamp":%d}`, strings.Join(data, ","), int64(1536336916333)-int64(requests))
+	})
+
+	var ids []string
+	for i := 0; i < 250; i++ {
+		if i%50 == 7 {
+			ids = append(ids, fmt.Sprintf("unknown-%d", i))
+		} else {
+			ids = append(ids, fmt.Sprintf("asset-%d", i))
+		}
+	}
+	ids = append(ids, "asset-0")
+
+	var meta ResponseMeta
+	assets, missing, timestamp, err := client.AssetsByIDsWithContext(WithResponseMeta(context.Background(), &meta), ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests != 3 {
+		t.Errorf("Expected 250 ids to be split across 3 requests but made %d", requests)
+	}
+	if meta.Attempts != 3 || meta.Timestamp.String() != "1536336916330" {
+		t.Errorf("Expected the meta to cover every request and describe the last but got %+v", meta)
+	}
+	if len(assets) != 245 {
+		t.Fatalf("Expected 245 assets but got %d", len(assets))
+	}
+	if assets[0].ID != "asset-0" || assets[7].ID != "asset-8" || assets[244].ID != "asset-249" {
+		t.Errorf("Expected assets in requested order but got %s, %s, %s", assets[0].ID, assets[7].ID, assets[244].ID)
+	}
+	expectedMissing := "unknown-7,unknown-57,unknown-107,unknown-157,unknown-207"
+	if strings.Join(missing, ",") != expectedMissing {
+		t.Errorf("Expected missing ids %s but got %v", expectedMissing, missing)
+	}
+	if timestamp.String() != "1536336916330" {
+		t.Errorf("Expected the oldest timestamp but got %s", timestamp)
+	}
+}
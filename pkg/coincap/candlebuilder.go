@@ -0,0 +1,309 @@
+package coincap
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LiveCandle is a candle built from the trades feed. Partial candles are sent as trades
+// come in and a final candle once no more trades are accepted for its period
+type LiveCandle struct {
+	Candle
+	Trades int  // number of trades the candle was built from
+	Final  bool // whether the candle is complete and will not change anymore
+}
+
+// CandleBuilderOptions configures a CandleBuilder
+type CandleBuilderOptions struct {
+	// Grace is how long after a period ends trades for it are still accepted, since exchanges
+	// report trades late and out of order. Periods are finalized once Grace has passed since
+	// their end, by the clock or by the time of a later trade, whichever comes first.
+	// Later trades for them are counted by Late and discarded
+	Grace time.Duration
+	// BufferSize is the number of candles buffered for the consumer. Defaults to 64
+	BufferSize int
+	// Stream configures the connection to the trades feed
+	Stream *StreamOptions
+}
+
+// CandleBuilder builds live candles for a market from the wss://ws.coincap.io/trades/{exchange} feed.
+// The candles follow the semantics of Candles, so history from Candles can be continued with them:
+// Period is the start of the interval in UTC, prices are in the quote asset and volume is in
+// the base asset. Like Candles no candle is produced for periods without trades.
+// Use it like
+//
+//	builder, err := client.CandleBuilder(ctx, &coincap.CandlesRequest{...}, nil)
+//	...
+//	for candle := range builder.Candles() {
+//		if candle.Final {
+//			...
+//		}
+//	}
+type CandleBuilder struct {
+	late uint64 // trades too late to count, atomic and first for alignment
+
+	stream   *TradeStream
+	cancel   context.CancelFunc
+	base     string
+	quote    string
+	interval Interval
+	grace    time.Duration
+	clock    clock
+	candles  chan LiveCandle
+
+	mu        sync.Mutex
+	watermark time.Time                // latest trade time seen, which decides when periods are final
+	open      map[int64]*candleBuilder // candles still accepting trades by period start in unix milliseconds
+}
+
+// candleBuilder accumulates the trades of one period
+type candleBuilder struct {
+	start, end             time.Time
+	open, high, low, close Decimal
+	volume                 Decimal
+	openAt, closeAt        time.Time // times of the trades open and close were taken from
+	trades                 int
+}
+
+// CandleBuilder connects to the trades feed of reqParams.ExchangeID and builds candles of
+// reqParams.Interval from the trades between reqParams.BaseID and reqParams.QuoteID.
+// The other fields of reqParams are ignored. The builder runs until ctx is done,
+// Close is called or the connection fails
+func (c *Client) CandleBuilder(ctx context.Context, reqParams *CandlesRequest, opts *CandleBuilderOptions) (*CandleBuilder, error) {
+	if reqParams.ExchangeID == "" || reqParams.BaseID == "" || reqParams.QuoteID == "" {
+		return nil, fmt.Errorf("ExchangeID, BaseID and QuoteID are required")
+	}
+	if !reqParams.Interval.ValidForCandles() {
+		return nil, fmt.Errorf("Interval %q is not supported for candles", reqParams.Interval)
+	}
+	if opts == nil {
+		opts = &CandleBuilderOptions{}
+	}
+	if opts.Grace < 0 {
+		return nil, fmt.Errorf("Grace must not be negative")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := c.TradeStream(ctx, reqParams.ExchangeID, opts.Stream)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	b := &CandleBuilder{
+		stream:   stream,
+		cancel:   cancel,
+		base:     reqParams.BaseID,
+		quote:    reqParams.QuoteID,
+		interval: reqParams.Interval,
+		grace:    opts.Grace,
+		clock:    c.clock,
+		candles:  make(chan LiveCandle, (&StreamOptions{BufferSize: opts.BufferSize}).bufferSize()),
+		open:     make(map[int64]*candleBuilder),
+	}
+	go b.run(ctx)
+	return b, nil
+}
+
+// run turns the trades of the market into candles until the stream stops. A timer finalizes
+// periods once their grace period is over, so the last candle is sent even if no trade follows
+func (b *CandleBuilder) run(ctx context.Context) {
+	defer close(b.candles)
+	defer b.cancel()
+
+	var (
+		trades   = b.stream.Trades()
+		deadline time.Time // when timer fires, zero if it is not set
+		timer    <-chan time.Time
+	)
+	for {
+		var candles []LiveCandle
+		select {
+		case trade, ok := <-trades:
+			if !ok {
+				return
+			}
+			if trade.Base != b.base || trade.Quote != b.quote || trade.Price.IsNull() || trade.Timestamp.IsZero() {
+				continue
+			}
+			candles = b.add(trade)
+		case <-timer:
+			deadline, timer = time.Time{}, nil
+			candles = b.expire()
+		}
+		for _, candle := range candles {
+			select {
+			case b.candles <- candle:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		// only rearm the timer when the next deadline moves, rather than for every trade
+		if next, ok := b.nextDeadline(); ok && !next.Equal(deadline) {
+			deadline, timer = next, b.clock.After(next.Sub(b.clock.Now()))
+		}
+	}
+}
+
+// add applies trade and returns the candles to send for it: the candles it finalized,
+// oldest first, followed by the partial candle it updated
+func (b *CandleBuilder) add(trade Trade) []LiveCandle {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	at := trade.Timestamp.Time
+	if at.After(b.watermark) {
+		b.watermark = at
+	}
+	out := b.finalize()
+
+	start := b.interval.Truncate(at).UTC()
+	end := start.Add(b.interval.Duration())
+	if !end.Add(b.grace).After(b.horizon()) {
+		// the period was finalized already
+		atomic.AddUint64(&b.late, 1)
+		return out
+	}
+
+	key := toMillis(start)
+	cb, ok := b.open[key]
+	if !ok {
+		cb = &candleBuilder{start: start, end: end}
+		b.open[key] = cb
+	}
+	cb.add(trade)
+	return append(out, cb.candle(false))
+}
+
+// expire finalizes the periods whose grace period the clock has passed
+func (b *CandleBuilder) expire() []LiveCandle {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.finalize()
+}
+
+// nextDeadline returns when the grace period of the earliest open period ends, if there is one
+func (b *CandleBuilder) nextDeadline() (time.Time, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var next time.Time
+	for _, cb := range b.open {
+		if d := cb.end.Add(b.grace); next.IsZero() || d.Before(next) {
+			next = d
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// horizon returns the time periods are final up to, counting their grace period: the later
+// of the watermark and the clock. b.mu must be held
+func (b *CandleBuilder) horizon() time.Time {
+	if now := b.clock.Now(); now.After(b.watermark) {
+		return now
+	}
+	return b.watermark
+}
+
+// finalize removes the candles whose period and grace period ended before the horizon
+// and returns them oldest first. b.mu must be held
+func (b *CandleBuilder) finalize() []LiveCandle {
+	horizon := b.horizon()
+	var keys []int64
+	for key, cb := range b.open {
+		if !cb.end.Add(b.grace).After(horizon) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	candles := make([]LiveCandle, 0, len(keys))
+	for _, key := range keys {
+		candles = append(candles, b.open[key].candle(true))
+		delete(b.open, key)
+	}
+	return candles
+}
+
+// add updates the candle with trade, which may be older than trades already added
+func (cb *candleBuilder) add(trade Trade) {
+	at, price := trade.Timestamp.Time, trade.Price
+	if cb.trades == 0 {
+		cb.open, cb.high, cb.low, cb.close = price, price, price, price
+		cb.volume = NewDecimal(0, 0)
+		cb.openAt, cb.closeAt = at, at
+	}
+	if at.Before(cb.openAt) {
+		cb.open, cb.openAt = price, at
+	}
+	// trades at the same time are taken to have happened in the order they arrived
+	if !at.Before(cb.closeAt) {
+		cb.close, cb.closeAt = price, at
+	}
+	if price.Cmp(cb.high) > 0 {
+		cb.high = price
+	}
+	if price.Cmp(cb.low) < 0 {
+		cb.low = price
+	}
+	if !trade.Volume.IsNull() {
+		cb.volume = cb.volume.Add(trade.Volume)
+	}
+	cb.trades++
+}
+
+// candle returns the current state of the candle
+func (cb *candleBuilder) candle(final bool) LiveCandle {
+	return LiveCandle{
+		Candle: Candle{
+			Open:   cb.open.String(),
+			High:   cb.high.String(),
+			Low:    cb.low.String(),
+			Close:  cb.close.String(),
+			Volume: cb.volume.String(),
+			Period: Timestamp{Time: cb.start},
+		},
+		Trades: cb.trades,
+		Final:  final,
+	}
+}
+
+// Candles returns the channel candles are delivered on. It is closed when the builder stops,
+// in which case candles that were not final yet are not sent again
+func (b *CandleBuilder) Candles() <-chan LiveCandle {
+	return b.candles
+}
+
+// Watermark returns the time of the latest trade seen. Periods ending more than Grace before it,
+// or before the current time, are final
+func (b *CandleBuilder) Watermark() time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.watermark
+}
+
+// Late returns the number of trades discarded because their period had been finalized
+func (b *CandleBuilder) Late() uint64 {
+	return atomic.LoadUint64(&b.late)
+}
+
+// Gaps returns the channel reconnections of the trades feed are reported on, if the
+// stream has a reconnect policy. Candles overlapping a gap may be missing trades
+func (b *CandleBuilder) Gaps() <-chan StreamGap {
+	return b.stream.Gaps()
+}
+
+// Err returns the error that stopped the builder, or nil if it is still running or
+// was stopped by Close or its context
+func (b *CandleBuilder) Err() error {
+	return b.stream.Err()
+}
+
+// Close stops the builder and closes the connection
+func (b *CandleBuilder) Close() error {
+	b.cancel()
+	return b.stream.Close()
+}
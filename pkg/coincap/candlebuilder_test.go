@@ -0,0 +1,146 @@
+package coincap
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// candleStart is the start of a minute in unix milliseconds
+const candleStart = 1562876340000
+
+// tradeAt returns a trade message for base/tether at the given seconds after candleStart
+func tradeAt(base string, seconds int, price, volume string) string {
+	return fmt.Sprintf(`{"exchange":"binance","base":%q,"quote":"tether","direction":"buy","price":%s,"volume":%s,"timestamp":%d,"priceUsd":%s}`,
+		base, price, volume, candleStart+int64(seconds)*1000, price)
+}
+
+// receiveCandle returns the next candle or fails the test after a second
+func receiveCandle(t *testing.T, candles <-chan LiveCandle) LiveCandle {
+	t.Helper()
+	select {
+	case candle, ok := <-candles:
+		if !ok {
+			t.Fatal("Expected a candle but the builder stopped")
+		}
+		return candle
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for a candle")
+	}
+	return LiveCandle{}
+}
+
+func TestCandleBuilder(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+	// stop the clock at the first trade so only the trades finalize periods
+	client.clock = &manualClock{now: fromMillis(candleStart)}
+
+	messages := []string{
+		tradeAt("bitcoin", 5, "10", "1"),
+		tradeAt("bitcoin", 20, "12", "0.5"),
+		tradeAt("bitcoin", 10, "8", "1"), // out of order
+		tradeAt("ethereum", 30, "200", "1"),
+		tradeAt("bitcoin", 65, "11", "2"),
+		tradeAt("bitcoin", 2, "9", "1"),   // late but within the grace period
+		tradeAt("bitcoin", 75, "13", "1"), // ends the grace period of the first minute
+		tradeAt("bitcoin", 30, "1", "1"),  // too late
+	}
+	serveWebsocket("/trades/binance", func(conn *websocket.Conn, req *http.Request) {
+		for _, msg := range messages {
+			conn.WriteMessage(websocket.TextMessage, []byte(msg))
+		}
+		drain(conn)
+	})
+
+	reqParams := &CandlesRequest{ExchangeID: "binance", BaseID: "bitcoin", QuoteID: "tether", Interval: Minute}
+	builder, err := client.CandleBuilder(context.Background(), reqParams, &CandleBuilderOptions{Grace: 10 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, second := fromMillis(candleStart), fromMillis(candleStart+60000)
+	expected := []LiveCandle{
+		{Candle: Candle{Open: "10", High: "10", Low: "10", Close: "10", Volume: "1", Period: Timestamp{first}}, Trades: 1},
+		{Candle: Candle{Open: "10", High: "12", Low: "10", Close: "12", Volume: "1.5", Period: Timestamp{first}}, Trades: 2},
+		{Candle: Candle{Open: "10", High: "12", Low: "8", Close: "12", Volume: "2.5", Period: Timestamp{first}}, Trades: 3},
+		{Candle: Candle{Open: "11", High: "11", Low: "11", Close: "11", Volume: "2", Period: Timestamp{second}}, Trades: 1},
+		{Candle: Candle{Open: "9", High: "12", Low: "8", Close: "12", Volume: "3.5", Period: Timestamp{first}}, Trades: 4},
+		{Candle: Candle{Open: "9", High: "12", Low: "8", Close: "12", Volume: "3.5", Period: Timestamp{first}}, Trades: 4, Final: true},
+		{Candle: Candle{Open: "11", High: "13", Low: "11", Close: "13", Volume: "3", Period: Timestamp{second}}, Trades: 2},
+	}
+	for i, e := range expected {
+		c := receiveCandle(t, builder.Candles())
+		if c.Open != e.Open || c.High != e.High || c.Low != e.Low || c.Close != e.Close || c.Volume != e.Volume ||
+			!c.Period.Equal(e.Period.Time) || c.Trades != e.Trades || c.Final != e.Final {
+			t.Errorf("%d: expected %+v but got %+v", i, e, c)
+		}
+	}
+
+	waitFor(t, "the late trade", func() bool { return builder.Late() == 1 })
+	if wm := builder.Watermark(); !wm.Equal(fromMillis(candleStart + 75000)) {
+		t.Errorf("Expected the watermark at the latest trade but got %v", wm)
+	}
+	builder.Close()
+	for c := range builder.Candles() {
+		t.Errorf("Expected no more candles but got %+v", c)
+	}
+	if builder.Err() != nil {
+		t.Errorf("Expected a clean shutdown but got %v", builder.Err())
+	}
+}
+
+func TestCandleBuilderFinalizesByClock(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+	clk := newFakeClock()
+	clk.now = fromMillis(candleStart)
+	client.clock = clk
+
+	// no trade follows the first, so only the clock can finalize its period
+	serveWebsocket("/trades/binance", func(conn *websocket.Conn, req *http.Request) {
+		conn.WriteMessage(websocket.TextMessage, []byte(tradeAt("bitcoin", 5, "10", "1")))
+		drain(conn)
+	})
+
+	reqParams := &CandlesRequest{ExchangeID: "binance", BaseID: "bitcoin", QuoteID: "tether", Interval: Minute}
+	builder, err := client.CandleBuilder(context.Background(), reqParams, &CandleBuilderOptions{Grace: 10 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer builder.Close()
+
+	if c := receiveCandle(t, builder.Candles()); c.Final || c.Trades != 1 {
+		t.Errorf("Expected the partial candle first but got %+v", c)
+	}
+	c := receiveCandle(t, builder.Candles())
+	if !c.Final || c.Trades != 1 || c.Open != "10" || !c.Period.Equal(fromMillis(candleStart)) {
+		t.Errorf("Expected the candle to be finalized by the clock but got %+v", c)
+	}
+	if sleeps := clk.Sleeps(); len(sleeps) != 1 || sleeps[0] != 70*time.Second {
+		t.Errorf("Expected a timer for the end of the grace period but got %v", sleeps)
+	}
+}
+
+func TestCandleBuilderInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		req  CandlesRequest
+		opts *CandleBuilderOptions
+	}{
+		{"no exchange", CandlesRequest{BaseID: "bitcoin", QuoteID: "tether", Interval: Minute}, nil},
+		{"no quote", CandlesRequest{ExchangeID: "binance", BaseID: "bitcoin", Interval: Minute}, nil},
+		{"bad interval", CandlesRequest{ExchangeID: "binance", BaseID: "bitcoin", QuoteID: "tether", Interval: "m2"}, nil},
+		{"negative grace", CandlesRequest{ExchangeID: "binance", BaseID: "bitcoin", QuoteID: "tether", Interval: Minute}, &CandleBuilderOptions{Grace: -time.Second}},
+	}
+	client := NewClient(nil)
+	for _, test := range tests {
+		if _, err := client.CandleBuilder(context.Background(), &test.req, test.opts); err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+	}
+}
@@ -1,22 +1,66 @@
 package coincap
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 // CandlesRequest contains the parameters you can use to customize a request for candle data from the /candles endpoint
 type CandlesRequest struct {
-	ExchangeID string   `json:"exchangeId"`       // search by unique exchange ID
-	BaseID     string   `json:"baseId"`           // return all results with this base id
-	QuoteID    string   `json:"quoteId"`          // return all results with this quote ID
-	Interval   Interval `json:"interval"`         // candle interval
-	Start      int      `json:"start,omitempty"`  // start time in unix milliseconds TODO: I should probably use time.Time or Timestamp here
-	End        int      `json:"end,omitempty"`    // end time in unix milliseconds TODO: same as above
-	Limit      int      `json:"limit,omitempty"`  // limit number of returned results (Max: 2000)
-	Offset     int      `json:"offset,omitempty"` // skip the first N entries of the result set
+	ExchangeID string     `json:"exchangeId"`          // search by unique exchange ID
+	BaseID     string     `json:"baseId"`              // return all results with this base id
+	QuoteID    string     `json:"quoteId"`             // return all results with this quote ID
+	Interval   Interval   `json:"interval"`            // candle interval
+	StartTime  *Timestamp `json:"startTime,omitempty"` // start time. A start within a candle is moved back to the start of that candle
+	EndTime    *Timestamp `json:"endTime,omitempty"`   // end time
+	Limit      int        `json:"limit,omitempty"`     // limit number of returned results (Max: 2000)
+	Offset     int        `json:"offset,omitempty"`    // skip the first N entries of the result set
+
+	// Deprecated: use StartTime. Start time in unix milliseconds, which overflows int on 32-bit platforms
+	Start int `json:"start,omitempty"`
+	// Deprecated: use EndTime. End time in unix milliseconds, which overflows int on 32-bit platforms
+	End int `json:"end,omitempty"`
+}
+
+// bounds returns the start and end of the requested range, either of which is the zero time if unset.
+// They are taken from StartTime and EndTime or the deprecated Start and End, setting both is an error
+// even if StartTime or EndTime is the zero time. A start that is not on an interval boundary is moved
+// back to the beginning of the candle it falls in, so the first candle returned may begin before it
+func (r *CandlesRequest) bounds() (time.Time, time.Time, error) {
+	var start, end time.Time
+	switch {
+	case r.StartTime != nil && r.Start != 0:
+		return start, end, fmt.Errorf("Only one of Start and StartTime may be set")
+	case r.StartTime != nil:
+		start = r.StartTime.Time
+	case r.Start > 0:
+		start = fromMillis(int64(r.Start))
+	}
+	switch {
+	case r.EndTime != nil && r.End != 0:
+		return start, end, fmt.Errorf("Only one of End and EndTime may be set")
+	case r.EndTime != nil:
+		end = r.EndTime.Time
+	case r.End > 0:
+		end = fromMillis(int64(r.End))
+	}
+
+	if !start.IsZero() && !end.IsZero() && !start.Before(end) {
+		return start, end, fmt.Errorf("Start %v must be before End %v", start, end)
+	}
+	// include the whole candle the range starts in rather than skipping it
+	if !start.IsZero() {
+		start = r.Interval.Truncate(start)
+	}
+	return start, end, nil
+}
+
+// unixMilli returns t in unix milliseconds
+func unixMilli(t time.Time) string {
+	return strconv.FormatInt(toMillis(t), 10)
 }
 
 // Candle represets historic market performance for an asset over a given timeframe
@@ -29,9 +73,40 @@ type Candle struct {
 	Period Timestamp `json:"period"` // timestamp for starting of that time period
 }
 
+// OpenDecimal parses Open as an exact Decimal
+func (c *Candle) OpenDecimal() (Decimal, error) {
+	return parseDecimalField("open", c.Open)
+}
+
+// HighDecimal parses High as an exact Decimal
+func (c *Candle) HighDecimal() (Decimal, error) {
+	return parseDecimalField("high", c.High)
+}
+
+// LowDecimal parses Low as an exact Decimal
+func (c *Candle) LowDecimal() (Decimal, error) {
+	return parseDecimalField("low", c.Low)
+}
+
+// CloseDecimal parses Close as an exact Decimal
+func (c *Candle) CloseDecimal() (Decimal, error) {
+	return parseDecimalField("close", c.Close)
+}
+
+// VolumeDecimal parses Volume as an exact Decimal
+func (c *Candle) VolumeDecimal() (Decimal, error) {
+	return parseDecimalField("volume", c.Volume)
+}
+
 // Candles returns all the market candle data for the provided exchange and parameters
 // The fields ExchangeID, BaseID, QuoteID, and Interval are required by the API
 func (c *Client) Candles(reqParams *CandlesRequest) ([]*Candle, *Timestamp, error) {
+	return c.CandlesWithContext(context.Background(), reqParams)
+}
+
+// CandlesWithContext is like Candles but carries ctx through the request,
+// allowing the caller to set deadlines or cancel the call
+func (c *Client) CandlesWithContext(ctx context.Context, reqParams *CandlesRequest) ([]*Candle, *Timestamp, error) {
 
 	// check required params
 	var err error
@@ -43,13 +118,19 @@ func (c *Client) Candles(reqParams *CandlesRequest) ([]*Candle, *Timestamp, erro
 		err = fmt.Errorf("QuoteID is required")
 	} else if string(reqParams.Interval) == "" {
 		err = fmt.Errorf("Interval is required")
+	} else if !reqParams.Interval.ValidForCandles() {
+		err = fmt.Errorf("Interval %q is not supported for candles", reqParams.Interval)
+	}
+	if err != nil {
+		return nil, nil, err
 	}
+	start, end, err := reqParams.bounds()
 	if err != nil {
 		return nil, nil, err
 	}
 
 	// Prepare the query
-	req, err := http.NewRequest("GET", c.baseURL+"/candles", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/candles", nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -60,11 +141,11 @@ func (c *Client) Candles(reqParams *CandlesRequest) ([]*Candle, *Timestamp, erro
 	params.Add("baseId", reqParams.BaseID)
 	params.Add("quoteId", reqParams.QuoteID)
 	params.Add("interval", string(reqParams.Interval))
-	if reqParams.Start > 0 {
-		params.Add("start", strconv.Itoa(reqParams.Start))
+	if !start.IsZero() {
+		params.Add("start", unixMilli(start))
 	}
-	if reqParams.End > 0 {
-		params.Add("end", strconv.Itoa(reqParams.End))
+	if !end.IsZero() {
+		params.Add("end", unixMilli(end))
 	}
 	if reqParams.Limit > 0 {
 		params.Add("limit", strconv.Itoa(reqParams.Limit))
@@ -75,14 +156,16 @@ func (c *Client) Candles(reqParams *CandlesRequest) ([]*Candle, *Timestamp, erro
 	req.URL.RawQuery = params.Encode()
 
 	// make the request
-	ccResp, err := c.fetchAndParse(req)
+	ccResp, err := c.fetchAndParse("/candles", req)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	// Unmarshal the deferred json from the data field
 	var candles []*Candle
-	json.Unmarshal(*ccResp.Data, &candles)
+	if err := decodeData("/candles", ccResp, &candles); err != nil {
+		return nil, nil, err
+	}
 
 	return candles, ccResp.Timestamp, nil
 }
@@ -2,8 +2,11 @@ package coincap
 
 import (
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"testing"
+	"time"
 )
 
 func TestCandles(t *testing.T) {
@@ -80,3 +83,87 @@ func TestFailRequiredParams(t *testing.T) {
 	}
 
 }
+
+func TestCandlesMalformed(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+	serveFixture("/candles", "candles_malformed.json")
+
+	req := CandlesRequest{
+		ExchangeID: "poloniex",
+		BaseID:     "ethereum",
+		QuoteID:    "bitcoin",
+		Interval:   FiveMinutes,
+	}
+	_, _, err := client.Candles(&req)
+	assertDecodeError(t, err, "/candles")
+}
+
+func TestCandlesTimeBounds(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	var start, end string
+	r.HandleFunc("/candles", func(w http.ResponseWriter, r *http.Request) {
+		start, end = r.URL.Query().Get("start"), r.URL.Query().Get("end")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, fixture("candles.json"))
+	})
+
+	base := CandlesRequest{
+		ExchangeID: "poloniex",
+		BaseID:     "ethereum",
+		QuoteID:    "bitcoin",
+		Interval:   FiveMinutes,
+	}
+	from := time.Date(2018, 9, 6, 14, 12, 30, 0, time.UTC)
+	to := from.Add(time.Hour)
+
+	tests := []struct {
+		name       string
+		start, end *Timestamp
+		startMs    int64 // deprecated Start, skipped on 32-bit platforms if too large
+		endMs      int64
+		wantStart  string
+		wantEnd    string
+		wantErr    bool
+	}{
+		// the start is moved back to the beginning of the 14:10 candle
+		{name: "misaligned start", start: &Timestamp{from}, end: &Timestamp{to}, wantStart: "1536243000000", wantEnd: "1536246750000"},
+		{name: "deprecated ints", startMs: 1536243150000, endMs: 1536246750000, wantStart: "1536243000000", wantEnd: "1536246750000"},
+		{name: "aligned start", start: &Timestamp{from.Add(-150 * time.Second)}, end: &Timestamp{to}, wantStart: "1536243000000", wantEnd: "1536246750000"},
+		{name: "open ended", start: &Timestamp{from}, wantStart: "1536243000000"},
+		{name: "unset"},
+		{name: "start after end", start: &Timestamp{to}, end: &Timestamp{from}, wantErr: true},
+		{name: "empty range", start: &Timestamp{from}, end: &Timestamp{from}, wantErr: true},
+		{name: "both starts", start: &Timestamp{from}, startMs: 1, wantErr: true},
+		{name: "both ends", end: &Timestamp{to}, endMs: 1, wantErr: true},
+		{name: "zero start time and start", start: &Timestamp{}, startMs: 1, wantErr: true},
+		{name: "zero end time and end", end: &Timestamp{}, endMs: 1, wantErr: true},
+	}
+	for _, test := range tests {
+		if strconv.IntSize < 64 && (test.startMs > math.MaxInt32 || test.endMs > math.MaxInt32) {
+			// the deprecated fields cannot hold millisecond times on 32-bit platforms
+			continue
+		}
+		start, end = "", ""
+		req := base
+		req.StartTime, req.EndTime = test.start, test.end
+		req.Start, req.End = int(test.startMs), int(test.endMs)
+
+		_, _, err := client.Candles(&req)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%s: expected an error", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if start != test.wantStart || end != test.wantEnd {
+			t.Errorf("%s: expected start %q and end %q but got %q and %q", test.name, test.wantStart, test.wantEnd, start, end)
+		}
+	}
+}
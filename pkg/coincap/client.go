@@ -3,38 +3,143 @@ package coincap
 
 import (
 	"compress/gzip"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"strings"
+	"time"
 )
 
 var baseURL = "https://api.coincap.io/v2"
 
-// Client is a rest client for the CoinCap V2 API
+// Client is a rest client for the CoinCap V2 API. A Client is configured once
+// at construction and is safe for concurrent use by multiple goroutines
 type Client struct {
-	baseURL    string
-	httpClient *http.Client
+	baseURL      string
+	websocketURL string
+	httpClient   *http.Client
+	userAgent    string
+	apiKey       string
+	timeout      time.Duration
+	retryPolicy  *RetryPolicy
+	rateLimiter  *RateLimiter
+	logger       Logger
+	middleware   []Middleware
+	interceptors []Interceptor
+	clock        clock
 }
 
 // NewClient returns a new client for interacting with the CoinCap API
-// If no httpClient is passed it will use http.DefaultClient
-func NewClient(httpClient *http.Client) *Client {
-	if httpClient == nil {
-		httpClient = http.DefaultClient
-	}
-	return &Client{
-		httpClient: httpClient,
+// If no httpClient is passed it will use http.DefaultClient.
+// Further configuration is provided with options, e.g.
+//
+//	client := coincap.NewClient(nil, coincap.WithAPIKey(key))
+func NewClient(httpClient *http.Client, opts ...Option) *Client {
+	return New(append([]Option{WithHTTPClient(httpClient)}, opts...)...)
+}
+
+// New returns a new client for interacting with the CoinCap API configured by the given options
+func New(opts ...Option) *Client {
+	c := &Client{
+		httpClient: http.DefaultClient,
 		baseURL:    baseURL,
+		clock:      realClock{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	// wrap the transport in a copy so the caller's http.Client is left untouched
+	if len(c.middleware) > 0 {
+		transport := c.httpClient.Transport
+		if transport == nil {
+			transport = http.DefaultTransport
+		}
+		for i := len(c.middleware) - 1; i >= 0; i-- {
+			transport = c.middleware[i](transport)
+		}
+		httpClient := *c.httpClient
+		httpClient.Transport = transport
+		c.httpClient = &httpClient
 	}
+	return c
 }
 
 // SetBaseURL allows the setting of custom api base paths
+//
+// Deprecated: use WithBaseURL. SetBaseURL is not safe to call while the client is in use
 func (c *Client) SetBaseURL(baseURL string) {
 	c.baseURL = baseURL
 }
 
+// SetRetryPolicy enables retrying of transient failures such as 429 and 502 responses.
+// Passing nil disables retries
+//
+// Deprecated: use WithRetryPolicy. SetRetryPolicy is not safe to call while the client is in use
+func (c *Client) SetRetryPolicy(policy *RetryPolicy) {
+	WithRetryPolicy(policy)(c)
+}
+
+// SetRateLimiter makes the client wait for the limiter before every request,
+// including retries. Passing nil disables client side rate limiting
+//
+// Deprecated: use WithRateLimiter. SetRateLimiter is not safe to call while the client is in use
+func (c *Client) SetRateLimiter(limiter *RateLimiter) {
+	c.rateLimiter = limiter
+}
+
+// SetAPIKey configures the CoinCap API key sent as a bearer token with every request.
+// The key is redacted from any error produced by the client
+//
+// Deprecated: use WithAPIKey. SetAPIKey is not safe to call while the client is in use
+func (c *Client) SetAPIKey(apiKey string) {
+	c.apiKey = apiKey
+}
+
+// logf writes a redacted message to the logger if one is configured
+func (c *Client) logf(format string, v ...interface{}) {
+	if c.logger == nil {
+		return
+	}
+	c.logger.Printf("%s", c.redact(fmt.Sprintf(format, v...)))
+}
+
+// redact removes the API key from a string that may be shown to users
+func (c *Client) redact(s string) string {
+	if c.apiKey == "" {
+		return s
+	}
+	return strings.Replace(s, c.apiKey, "[REDACTED]", -1)
+}
+
+// redactError hides the API key from the message of errors returned by the transport
+func (c *Client) redactError(err error) error {
+	if c.apiKey == "" || !strings.Contains(err.Error(), c.apiKey) {
+		return err
+	}
+	return &redactedError{msg: c.redact(err.Error()), err: err}
+}
+
+// redactAPIError hides the API key from the fields of an APIError, including the response headers
+func (c *Client) redactAPIError(apiErr *APIError) *APIError {
+	apiErr.Message = c.redact(apiErr.Message)
+	apiErr.Body = c.redact(apiErr.Body)
+	apiErr.URL = c.redact(apiErr.URL)
+	if c.apiKey != "" && apiErr.Header != nil {
+		header := make(http.Header, len(apiErr.Header))
+		for key, values := range apiErr.Header {
+			for _, value := range values {
+				header.Add(key, c.redact(value))
+			}
+		}
+		apiErr.Header = header
+	}
+	return apiErr
+}
+
 // Every coincap response has a top level entry called data
 // and a unix timestamp in milliseconds
 type coincapResp struct {
@@ -43,17 +148,139 @@ type coincapResp struct {
 }
 
 // fetchAndParse returns the json below the top level "data" key
-// returned by the coincap api
-func (c *Client) fetchAndParse(req *http.Request) (*coincapResp, error) {
+// returned by the coincap api. The request's context is honored for both
+// the round trip and reading the (possibly compressed) response body.
+// The call passes through the client's interceptors and failed attempts are
+// retried according to the client's RetryPolicy.
+// endpoint names the api path template (e.g. "/assets/{id}") for error reporting
+func (c *Client) fetchAndParse(endpoint string, req *http.Request) (*coincapResp, error) {
+	// bound the whole call, including retries, by the configured timeout
+	if c.timeout > 0 {
+		ctx, cancel := context.WithTimeout(req.Context(), c.timeout)
+		defer cancel()
+		req = req.WithContext(ctx)
+	}
+
 	// add the gzip compression header
 	req.Header.Add("Accept-Encoding", "gzip")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
 
-	// make request to the api and read the response
-	resp, err := c.httpClient.Do(req)
+	// report details of the response if the caller asked for them
+	meta := responseMetaFromContext(req.Context())
+	if meta != nil {
+		start := c.clock.Now()
+		*meta = ResponseMeta{URL: c.redact(req.URL.String()), RateLimitLimit: -1, RateLimitRemaining: -1}
+		defer func() {
+			meta.Latency = c.clock.Now().Sub(start)
+		}()
+	}
+
+	call := &Call{Endpoint: endpoint, Request: req}
+	res, err := c.intercept(call, c.send)
+	if err != nil {
+		return nil, c.redactError(err)
+	}
+	req, resp, body := call.Request, res.Response, res.Body
+	if meta != nil {
+		// describe the response returned, which an interceptor may have answered
+		// itself, e.g. from a cache, or replaced
+		meta.setResponse(resp)
+		meta.URL = c.redact(meta.URL)
+	}
+	if resp.StatusCode != 200 {
+		return nil, c.redactAPIError(newAPIError(resp, body))
+	}
+
+	// parse the result
+	ccResp := new(coincapResp)
+	if err := json.Unmarshal(body, ccResp); err != nil {
+		return nil, newDecodeError(endpoint, err)
+	}
+
+	if meta != nil {
+		meta.Timestamp = ccResp.Timestamp
+	}
+
+	// ensure we got both the data object and the timestamp
+	if ccResp.Data == nil {
+		return ccResp, &MissingDataError{URL: c.redact(req.URL.String())}
+	}
+	if ccResp.Timestamp == nil {
+		return ccResp, &MissingTimestampError{URL: c.redact(req.URL.String())}
+	}
+
+	return ccResp, nil
+}
+
+// send is the innermost CallHandler. It makes requests to the api until one
+// succeeds or we run out of retries
+func (c *Client) send(call *Call) (*CallResponse, error) {
+	req := call.Request
+	meta := responseMetaFromContext(req.Context())
+
+	var (
+		resp *http.Response
+		body []byte
+		err  error
+	)
+	for attempt := 1; ; attempt++ {
+		resp, body, err = c.roundTrip(req.Clone(req.Context()))
+		if meta != nil {
+			meta.Attempts = attempt
+			if resp != nil {
+				meta.setResponse(resp)
+				meta.URL = c.redact(meta.URL)
+			}
+		}
+		wait, retry := c.retryPolicy.shouldRetry(attempt, resp, err, c.clock.Now())
+		if !retry {
+			break
+		}
+		if err != nil {
+			c.logf("coincap: GET %s failed: %v, retrying in %s (attempt %d)", req.URL, err, wait, attempt)
+		} else {
+			c.logf("coincap: GET %s returned %d, retrying in %s (attempt %d)", req.URL, resp.StatusCode, wait, attempt)
+		}
+		if err := sleep(req.Context(), c.clock, wait); err != nil {
+			return nil, err
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
+	return &CallResponse{Response: resp, Body: body}, nil
+}
+
+// decodeData unmarshals the deferred json from the data field of a response into v
+func decodeData(endpoint string, ccResp *coincapResp, v interface{}) error {
+	if err := json.Unmarshal(*ccResp.Data, v); err != nil {
+		return newDecodeError(endpoint, err)
+	}
+	return nil
+}
+
+// roundTrip makes a single request to the api and reads the full response body
+func (c *Client) roundTrip(req *http.Request) (*http.Response, []byte, error) {
+	// stay under the request quota
+	if c.rateLimiter != nil {
+		if err := c.rateLimiter.Wait(req.Context()); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
 	defer resp.Body.Close()
+	if c.rateLimiter != nil {
+		c.rateLimiter.Update(resp.Header)
+	}
 
 	// check if the server sent compressed data
 	var reader io.ReadCloser
@@ -62,7 +289,7 @@ func (c *Client) fetchAndParse(req *http.Request) (*coincapResp, error) {
 		// if the content encoding was gzip instantiate a new gzip reader
 		reader, err = gzip.NewReader(resp.Body)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		defer reader.Close()
 	default:
@@ -70,28 +297,24 @@ func (c *Client) fetchAndParse(req *http.Request) (*coincapResp, error) {
 		reader = resp.Body
 	}
 
-	// now read the body out of the reader
-	body, err := ioutil.ReadAll(reader)
+	// now read the body out of the reader, aborting if the context is done
+	body, err := ioutil.ReadAll(&ctxReader{ctx: req.Context(), r: reader})
 	if err != nil {
-		return nil, err
-	}
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("Error received status: %d, with body: %s", resp.StatusCode, string(body))
+		return nil, nil, err
 	}
+	return resp, body, nil
+}
 
-	// parse the result
-	ccResp := new(coincapResp)
-	if err := json.Unmarshal(body, ccResp); err != nil {
-		return nil, err
-	}
+// ctxReader wraps a reader and stops reading once its context is done
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
 
-	// ensure we got both the data object and the timestamp
-	if ccResp.Data == nil {
-		return ccResp, fmt.Errorf(`Response is missing "data" payload`)
-	}
-	if ccResp.Timestamp == nil {
-		return ccResp, fmt.Errorf(`Response is missing required "timestamp"`)
+// Read implements io.Reader
+func (cr *ctxReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
 	}
-
-	return ccResp, nil
+	return cr.r.Read(p)
 }
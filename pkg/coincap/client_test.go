@@ -0,0 +1,135 @@
+package coincap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContextDeadline(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	// never respond until the client gives up
+	r.HandleFunc("/rates", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, _, err := client.RatesWithContext(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded but got: %v", err)
+	}
+}
+
+func TestContextCanceledBeforeRequest(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, _, err := client.AssetByIDWithContext(ctx, "bitcoin")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context canceled but got: %v", err)
+	}
+}
+
+func TestCtxReaderStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cr := &ctxReader{ctx: ctx, r: strings.NewReader("some body")}
+
+	buf := make([]byte, 4)
+	if _, err := cr.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if _, err := cr.Read(buf); err != context.Canceled {
+		t.Errorf("Expected read to fail with context canceled but got: %v", err)
+	}
+}
+
+func TestAPIKeySentOnEveryEndpoint(t *testing.T) {
+	teardown := setup(WithAPIKey("secret-key"))
+	defer teardown()
+
+	routes := map[string]string{
+		"/assets":              "assets.json",
+		"/assets/{id}":         "assetByID.json",
+		"/assets/{id}/history": "assetHistory.json",
+		"/assets/{id}/markets": "assetMarkets.json",
+		"/candles":             "candles.json",
+		"/markets":             "markets.json",
+		"/exchanges":           "exchange.json",
+		"/exchanges/{id}":      "exchangeByID.json",
+		"/rates":               "rates.json",
+		"/rates/{id}":          "ratesByID.json",
+	}
+	seen := map[string]string{}
+	for route, name := range routes {
+		route, name := route, name
+		r.HandleFunc(route, func(w http.ResponseWriter, r *http.Request) {
+			seen[route] = r.Header.Get("Authorization")
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprint(w, fixture(name))
+		})
+	}
+
+	client.Assets(&AssetsRequest{})
+	client.AssetByID("bitcoin")
+	client.AssetHistoryByID("bitcoin", &AssetHistoryRequest{})
+	client.AssetMarketsByID("bitcoin", &AssetMarketsRequest{})
+	client.Candles(&CandlesRequest{ExchangeID: "poloniex", BaseID: "ethereum", QuoteID: "bitcoin", Interval: Hour})
+	client.Markets(&MarketsRequest{})
+	client.Exchanges()
+	client.ExchangeByID("gdax")
+	client.Rates()
+	client.RateByID("bitcoin")
+
+	for route := range routes {
+		got, ok := seen[route]
+		if !ok {
+			t.Errorf("Endpoint %s was never called", route)
+			continue
+		}
+		if got != "Bearer secret-key" {
+			t.Errorf("Expected %s to send the API key but Authorization was %q", route, got)
+		}
+	}
+}
+
+func TestAPIKeyRedactedFromErrors(t *testing.T) {
+	teardown := setup(WithAPIKey("secret-key"))
+	defer teardown()
+	r.HandleFunc("/rates", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Rejected-Key", "secret-key")
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error":"invalid key secret-key"}`)
+	})
+	r.HandleFunc("/assets/{id}", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"timestamp":1536336916333}`)
+	})
+
+	_, _, err := client.Rates()
+	if err == nil {
+		t.Fatal("Expected an error for the rejected key")
+	}
+	if strings.Contains(err.Error(), "secret-key") {
+		t.Errorf("Expected API key to be redacted but got: %v", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || strings.Contains(fmt.Sprint(apiErr.Header), "secret-key") {
+		t.Errorf("Expected API key to be redacted from the headers but got %v", apiErr)
+	}
+
+	// a caller may pass the key where it ends up in the URL
+	_, _, err = client.AssetByID("secret-key")
+	var missing *MissingDataError
+	if !errors.As(err, &missing) || strings.Contains(missing.URL, "secret-key") {
+		t.Errorf("Expected API key to be redacted from the missing data URL but got %v", err)
+	}
+}
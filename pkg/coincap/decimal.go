@@ -0,0 +1,368 @@
+package coincap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Decimal is an exact arbitrary precision decimal number. CoinCap encodes all of
+// its numbers as strings and Decimal parses them without any floating point rounding.
+// A Decimal may be null, which CoinCap uses for unknown values such as the max supply
+// of an asset without a cap. The zero value is a valid, non-null 0.
+// Decimals are immutable and safe for concurrent use
+type Decimal struct {
+	value *big.Int // unscaled value, nil is treated as 0
+	scale int32    // number of digits after the decimal point
+	null  bool     // whether this represents a json null
+}
+
+var (
+	bigTen  = big.NewInt(10)
+	bigZero = new(big.Int)
+)
+
+// NewDecimal returns the decimal value * 10^-scale, e.g. NewDecimal(12345, 2) is 123.45
+func NewDecimal(value int64, scale int32) Decimal {
+	d := Decimal{value: big.NewInt(value), scale: scale}
+	if scale < 0 {
+		d = d.rescale(0)
+	}
+	return d
+}
+
+// NullDecimal returns a null Decimal
+func NullDecimal() Decimal {
+	return Decimal{null: true}
+}
+
+// maxDecimalScale bounds the number of digits after the decimal point, and before it for
+// exponents, that ParseDecimal accepts. Without a bound an input like "1e2000000000" makes
+// parsing or later arithmetic compute powers of ten hundreds of megabytes large
+const maxDecimalScale = 10000
+
+// ParseDecimal parses a decimal number such as "-1234.5678" or "1.5e-7".
+// An empty string or "null" is parsed as a null Decimal. Numbers needing more than
+// 10000 digits after the decimal point or an exponent above 10000 are rejected
+func ParseDecimal(s string) (Decimal, error) {
+	if s == "" || s == "null" {
+		return NullDecimal(), nil
+	}
+
+	// split off the exponent
+	mantissa, exp := s, int64(0)
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		var err error
+		mantissa = s[:i]
+		exp, err = strconv.ParseInt(s[i+1:], 10, 32)
+		if err != nil {
+			return Decimal{}, fmt.Errorf("Invalid decimal %q: bad exponent", s)
+		}
+	}
+
+	// drop the decimal point, remembering how many digits followed it
+	scale := int64(0)
+	if i := strings.IndexByte(mantissa, '.'); i >= 0 {
+		scale = int64(len(mantissa) - i - 1)
+		mantissa = mantissa[:i] + mantissa[i+1:]
+	}
+
+	// big.Int accepts a sign so make sure what remains is only digits
+	digits := strings.TrimLeft(mantissa, "+-")
+	if len(mantissa)-len(digits) > 1 || digits == "" || strings.TrimLeft(digits, "0123456789") != "" {
+		return Decimal{}, fmt.Errorf("Invalid decimal %q", s)
+	}
+	value, ok := new(big.Int).SetString(mantissa, 10)
+	if !ok {
+		return Decimal{}, fmt.Errorf("Invalid decimal %q", s)
+	}
+
+	scale -= exp
+	if scale > maxDecimalScale || scale < -maxDecimalScale {
+		return Decimal{}, fmt.Errorf("Invalid decimal %q: exponent out of range", s)
+	}
+	d := Decimal{value: value, scale: int32(scale)}
+	if d.scale < 0 {
+		d = d.rescale(0)
+	}
+	return d, nil
+}
+
+// MustParseDecimal is like ParseDecimal but panics if s is invalid
+func MustParseDecimal(s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// parseDecimalField parses a numeric string field of an API response
+func parseDecimalField(name, s string) (Decimal, error) {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		return Decimal{}, fmt.Errorf("Error parsing %s: %v", name, err)
+	}
+	return d, nil
+}
+
+// IsNull reports whether d is null
+func (d Decimal) IsNull() bool {
+	return d.null
+}
+
+// Scale returns the number of digits after the decimal point
+func (d Decimal) Scale() int32 {
+	return d.scale
+}
+
+// unscaled returns the unscaled value of d, never nil
+func (d Decimal) unscaled() *big.Int {
+	if d.value == nil {
+		return bigZero
+	}
+	return d.value
+}
+
+// rescale returns d with the given scale. Digits are truncated if the scale is reduced
+func (d Decimal) rescale(scale int32) Decimal {
+	if d.scale == scale {
+		return d
+	}
+	value := new(big.Int)
+	if scale > d.scale {
+		value.Mul(d.unscaled(), pow10(int64(scale)-int64(d.scale)))
+	} else {
+		value.Quo(d.unscaled(), pow10(int64(d.scale)-int64(scale)))
+	}
+	return Decimal{value: value, scale: scale}
+}
+
+// pow10 returns 10^n
+func pow10(n int64) *big.Int {
+	return new(big.Int).Exp(bigTen, big.NewInt(n), nil)
+}
+
+// align returns d and d2 rescaled to the larger of their scales
+func align(d, d2 Decimal) (Decimal, Decimal) {
+	if d.scale > d2.scale {
+		return d, d2.rescale(d.scale)
+	}
+	return d.rescale(d2.scale), d2
+}
+
+// Add returns d + d2. The result is null if either operand is null
+func (d Decimal) Add(d2 Decimal) Decimal {
+	if d.null || d2.null {
+		return NullDecimal()
+	}
+	a, b := align(d, d2)
+	return Decimal{value: new(big.Int).Add(a.unscaled(), b.unscaled()), scale: a.scale}
+}
+
+// Sub returns d - d2. The result is null if either operand is null
+func (d Decimal) Sub(d2 Decimal) Decimal {
+	return d.Add(d2.Neg())
+}
+
+// Mul returns d * d2. The result is null if either operand is null
+func (d Decimal) Mul(d2 Decimal) Decimal {
+	if d.null || d2.null {
+		return NullDecimal()
+	}
+	return Decimal{value: new(big.Int).Mul(d.unscaled(), d2.unscaled()), scale: d.scale + d2.scale}
+}
+
+// Div returns d / d2 rounded half away from zero to the given number of decimal places,
+// which like for Round may be negative. The result is null if either operand is null. Div panics if d2 is zero
+func (d Decimal) Div(d2 Decimal, places int32) Decimal {
+	if d.null || d2.null {
+		return NullDecimal()
+	}
+	if d2.Sign() == 0 {
+		panic("coincap: division of Decimal by zero")
+	}
+
+	// d/d2 * 10^places = d.value/d2.value * 10^(places - d.scale + d2.scale)
+	num := new(big.Int).Set(d.unscaled())
+	den := new(big.Int).Set(d2.unscaled())
+	if e := int64(places) - int64(d.scale) + int64(d2.scale); e >= 0 {
+		num.Mul(num, pow10(e))
+	} else {
+		den.Mul(den, pow10(-e))
+	}
+	return Decimal{value: quoRound(num, den), scale: places}
+}
+
+// quoRound returns num / den rounded half away from zero
+func quoRound(num, den *big.Int) *big.Int {
+	q, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+	if rem.Sign() == 0 {
+		return q
+	}
+	// round up in magnitude if the remainder is at least half the divisor
+	twice := new(big.Int).Abs(rem)
+	twice.Lsh(twice, 1)
+	if twice.Cmp(new(big.Int).Abs(den)) >= 0 {
+		if (num.Sign() < 0) != (den.Sign() < 0) {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q
+}
+
+// Neg returns -d
+func (d Decimal) Neg() Decimal {
+	if d.null {
+		return d
+	}
+	return Decimal{value: new(big.Int).Neg(d.unscaled()), scale: d.scale}
+}
+
+// Abs returns the absolute value of d
+func (d Decimal) Abs() Decimal {
+	if d.null {
+		return d
+	}
+	return Decimal{value: new(big.Int).Abs(d.unscaled()), scale: d.scale}
+}
+
+// Round returns d rounded half away from zero to the given number of decimal places.
+// Negative places round to tens, hundreds and so on, e.g. Round(-1) of 123 is 120
+func (d Decimal) Round(places int32) Decimal {
+	if d.null || d.scale <= places {
+		return d
+	}
+	divisor := pow10(int64(d.scale) - int64(places))
+	return Decimal{value: quoRound(d.unscaled(), divisor), scale: places}
+}
+
+// Sign returns -1, 0 or 1 depending on the sign of d. A null Decimal has sign 0
+func (d Decimal) Sign() int {
+	if d.null {
+		return 0
+	}
+	return d.unscaled().Sign()
+}
+
+// IsZero reports whether d is a non-null zero
+func (d Decimal) IsZero() bool {
+	return !d.null && d.Sign() == 0
+}
+
+// Cmp compares d and d2 and returns -1, 0 or 1 if d is less than, equal or greater than d2.
+// Null is equal to null and less than any number
+func (d Decimal) Cmp(d2 Decimal) int {
+	switch {
+	case d.null && d2.null:
+		return 0
+	case d.null:
+		return -1
+	case d2.null:
+		return 1
+	}
+	a, b := align(d, d2)
+	return a.unscaled().Cmp(b.unscaled())
+}
+
+// Equal reports whether d and d2 represent the same number, ignoring trailing zeros
+func (d Decimal) Equal(d2 Decimal) bool {
+	return d.Cmp(d2) == 0
+}
+
+// Float64 returns the float64 nearest to d. A null Decimal returns 0
+func (d Decimal) Float64() float64 {
+	if d.null {
+		return 0
+	}
+	f, _ := strconv.ParseFloat(d.String(), 64)
+	return f
+}
+
+// Int64 returns the integer part of d, truncated towards zero.
+// The result is undefined if it does not fit in an int64
+func (d Decimal) Int64() int64 {
+	if d.null {
+		return 0
+	}
+	return d.rescale(0).unscaled().Int64()
+}
+
+// String returns d in plain decimal notation keeping all of its digits,
+// e.g. "20524490.0000000000000000". A null Decimal returns an empty string
+func (d Decimal) String() string {
+	if d.null {
+		return ""
+	}
+	digits := new(big.Int).Abs(d.unscaled()).String()
+	sign := ""
+	if d.unscaled().Sign() < 0 {
+		sign = "-"
+	}
+	if d.scale <= 0 {
+		// a negative scale, e.g. from Round(-1), counts the zeros before the decimal point
+		if d.unscaled().Sign() != 0 {
+			digits += strings.Repeat("0", -int(d.scale))
+		}
+		return sign + digits
+	}
+
+	// pad with zeros so there is at least one digit before the decimal point
+	if len(digits) <= int(d.scale) {
+		digits = strings.Repeat("0", int(d.scale)-len(digits)+1) + digits
+	}
+	point := len(digits) - int(d.scale)
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// StringFixed returns d rounded half away from zero to exactly the given number of decimal places,
+// e.g. StringFixed(2) of 3.0796 is "3.08". A null Decimal returns an empty string
+func (d Decimal) StringFixed(places int32) string {
+	if d.null {
+		return ""
+	}
+	if places < 0 {
+		places = 0
+	}
+	rounded := d.Round(places)
+	return rounded.rescale(places).String()
+}
+
+// MarshalJSON implements json.Marshaler. Decimals are encoded as strings
+// like the CoinCap API does, or null
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	if d.null {
+		return []byte("null"), nil
+	}
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler and accepts strings, bare numbers and null
+func (d *Decimal) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if bytes.Equal(b, []byte("null")) {
+		*d = NullDecimal()
+		return nil
+	}
+
+	s := string(b)
+	if len(b) > 0 && b[0] == '"' {
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+		if s == "" {
+			return fmt.Errorf("Invalid decimal %q", s)
+		}
+	}
+	parsed, err := ParseDecimal(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
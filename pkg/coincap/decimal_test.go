@@ -0,0 +1,196 @@
+package coincap
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected string
+		null     bool
+		fail     bool
+	}{
+		{in: "20524490.0000000000000000", expected: "20524490.0000000000000000"},
+		{in: "-4.4965710648108234", expected: "-4.4965710648108234"},
+		{in: "0.000001", expected: "0.000001"},
+		{in: "+12", expected: "12"},
+		{in: ".5", expected: "0.5"},
+		{in: "1.5e-7", expected: "0.00000015"},
+		{in: "6.25E3", expected: "6250"},
+		{in: "123456789012345678901234567890.123456789", expected: "123456789012345678901234567890.123456789"},
+		{in: "", null: true},
+		{in: "null", null: true},
+		{in: "abc", fail: true},
+		{in: "1.2.3", fail: true},
+		{in: "--1", fail: true},
+		{in: "1e", fail: true},
+		{in: ".", fail: true},
+		{in: "1e10000", expected: "1" + strings.Repeat("0", 10000)},
+		{in: "1e10001", fail: true},
+		{in: "1e2000000000", fail: true},
+		{in: "1e-2000000000", fail: true},
+		{in: "0." + strings.Repeat("0", 10000) + "1", fail: true},
+	}
+	for _, tt := range tests {
+		d, err := ParseDecimal(tt.in)
+		if tt.fail {
+			if err == nil {
+				t.Errorf("ParseDecimal(%q) expected an error but got %s", tt.in, d)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseDecimal(%q) unexpected error: %v", tt.in, err)
+			continue
+		}
+		if d.IsNull() != tt.null {
+			t.Errorf("ParseDecimal(%q).IsNull() = %v", tt.in, d.IsNull())
+		}
+		if d.String() != tt.expected {
+			t.Errorf("ParseDecimal(%q) = %s, expected %s", tt.in, d, tt.expected)
+		}
+	}
+}
+
+func TestDecimalJSONRejectsHugeExponents(t *testing.T) {
+	var d Decimal
+	for _, in := range []string{`"1e2000000000"`, `1e-2000000000`} {
+		if err := json.Unmarshal([]byte(in), &d); err == nil {
+			t.Errorf("Expected %s to be rejected but got %s", in, d)
+		}
+	}
+}
+
+func TestDecimalArithmetic(t *testing.T) {
+	a := MustParseDecimal("0.1")
+	b := MustParseDecimal("0.2")
+
+	// the classic float rounding error does not happen
+	if sum := a.Add(b); sum.String() != "0.3" || !sum.Equal(MustParseDecimal("0.30")) {
+		t.Errorf("Expected 0.1 + 0.2 = 0.3 but got %s", sum)
+	}
+	if diff := a.Sub(b); diff.String() != "-0.1" {
+		t.Errorf("Expected 0.1 - 0.2 = -0.1 but got %s", diff)
+	}
+	if prod := MustParseDecimal("20524490.5").Mul(MustParseDecimal("3.0796037735108296")); prod.String() != "63207298.39318717377231880" {
+		t.Errorf("Unexpected product %s", prod)
+	}
+	if q := MustParseDecimal("2").Div(MustParseDecimal("3"), 4); q.String() != "0.6667" {
+		t.Errorf("Expected 2/3 = 0.6667 but got %s", q)
+	}
+	if q := MustParseDecimal("-1").Div(MustParseDecimal("8"), 2); q.String() != "-0.13" {
+		t.Errorf("Expected -1/8 = -0.13 but got %s", q)
+	}
+	if q := MustParseDecimal("1000").Div(MustParseDecimal("0.001"), 0); q.String() != "1000000" {
+		t.Errorf("Expected 1000/0.001 = 1000000 but got %s", q)
+	}
+	if abs := MustParseDecimal("-3.5").Abs(); abs.String() != "3.5" {
+		t.Errorf("Expected |-3.5| = 3.5 but got %s", abs)
+	}
+	if NewDecimal(12345, 2).String() != "123.45" || NewDecimal(5, -2).String() != "500" {
+		t.Errorf("Unexpected values from NewDecimal")
+	}
+
+	// null propagates
+	if !a.Add(NullDecimal()).IsNull() || !NullDecimal().Mul(b).IsNull() || !a.Div(NullDecimal(), 2).IsNull() {
+		t.Errorf("Expected arithmetic with null to produce null")
+	}
+}
+
+func TestDecimalDivByZero(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected division by zero to panic")
+		}
+	}()
+	MustParseDecimal("1").Div(Decimal{}, 2)
+}
+
+func TestDecimalRoundingAndFormatting(t *testing.T) {
+	tests := []struct {
+		in     string
+		places int32
+		fixed  string
+	}{
+		{"3.0796037735108296", 2, "3.08"},
+		{"-4.4965710648108234", 3, "-4.497"},
+		{"2.5", 0, "3"},
+		{"-2.5", 0, "-3"},
+		{"1.2", 4, "1.2000"},
+		{"0.0004", 3, "0.000"},
+	}
+	for _, tt := range tests {
+		if got := MustParseDecimal(tt.in).StringFixed(tt.places); got != tt.fixed {
+			t.Errorf("StringFixed(%s, %d) = %s, expected %s", tt.in, tt.places, got, tt.fixed)
+		}
+	}
+
+	rounded := []struct {
+		d        Decimal
+		expected string
+	}{
+		{MustParseDecimal("123").Round(-1), "120"},
+		{MustParseDecimal("-155.5").Round(-1), "-160"},
+		{MustParseDecimal("49").Round(-2), "0"},
+		{MustParseDecimal("1234").Div(MustParseDecimal("2"), -2), "600"},
+	}
+	for _, tt := range rounded {
+		if got := tt.d.String(); got != tt.expected {
+			t.Errorf("Rounding to negative places gave %s, expected %s", got, tt.expected)
+		}
+		if tt.d.Float64() != MustParseDecimal(tt.expected).Float64() {
+			t.Errorf("Expected %s to convert to a float consistently", tt.expected)
+		}
+	}
+
+	d := MustParseDecimal("6460.9771089680171173")
+	if d.Float64() != 6460.977108968017 {
+		t.Errorf("Unexpected float %v", d.Float64())
+	}
+	if d.Int64() != 6460 || d.Neg().Int64() != -6460 {
+		t.Errorf("Unexpected integer part %d", d.Int64())
+	}
+}
+
+func TestDecimalCmp(t *testing.T) {
+	one := MustParseDecimal("1.00")
+	if one.Cmp(MustParseDecimal("1")) != 0 || one.Cmp(MustParseDecimal("1.01")) != -1 || one.Cmp(MustParseDecimal("-2")) != 1 {
+		t.Errorf("Unexpected comparison results")
+	}
+	if NullDecimal().Cmp(NullDecimal()) != 0 || NullDecimal().Cmp(one) != -1 || one.Cmp(NullDecimal()) != 1 {
+		t.Errorf("Unexpected comparison results with null")
+	}
+	if !(Decimal{}).IsZero() || NullDecimal().IsZero() {
+		t.Errorf("Expected the zero value to be zero and null not to be")
+	}
+}
+
+func TestDecimalJSON(t *testing.T) {
+	var v struct {
+		Quoted Decimal `json:"quoted"`
+		Bare   Decimal `json:"bare"`
+		Null   Decimal `json:"null"`
+	}
+	in := `{"quoted":"21000000.0000000000000000","bare":6470.98,"null":null}`
+	if err := json.Unmarshal([]byte(in), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Quoted.String() != "21000000.0000000000000000" || v.Bare.String() != "6470.98" || !v.Null.IsNull() {
+		t.Errorf("Unexpected decoded values %+v", v)
+	}
+
+	out, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"quoted":"21000000.0000000000000000","bare":"6470.98","null":null}`; string(out) != expected {
+		t.Errorf("Expected %s but got %s", expected, out)
+	}
+
+	if err := json.Unmarshal([]byte(`{"quoted":"not a number"}`), &v); err == nil {
+		t.Errorf("Expected invalid decimal to fail")
+	}
+}
@@ -0,0 +1,140 @@
+package coincap
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Sentinel errors that an *APIError matches with errors.Is depending on
+// the HTTP status code returned by the CoinCap API
+var (
+	ErrBadRequest   = errors.New("coincap: bad request")  // 400
+	ErrUnauthorized = errors.New("coincap: unauthorized") // 401 and 403
+	ErrNotFound     = errors.New("coincap: not found")    // 404
+	ErrRateLimited  = errors.New("coincap: rate limited") // 429
+	ErrServerError  = errors.New("coincap: server error") // 5xx
+)
+
+// APIError is returned when the CoinCap API responds with a non-200 status code
+type APIError struct {
+	StatusCode int         // HTTP status code of the response
+	Message    string      // value of the "error" key in the response body if present
+	Body       string      // raw response body
+	URL        string      // URL of the request that failed
+	Header     http.Header // headers of the response
+}
+
+// newAPIError builds an APIError from a failed response and its already read body
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Body:       string(body),
+		Header:     resp.Header,
+	}
+	if resp.Request != nil && resp.Request.URL != nil {
+		apiErr.URL = resp.Request.URL.String()
+	}
+
+	// CoinCap reports failures as {"error": "...", "timestamp": ...}
+	var errResp struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &errResp); err == nil {
+		apiErr.Message = errResp.Error
+	}
+	return apiErr
+}
+
+// Error implements error
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = e.Body
+	}
+	return fmt.Sprintf("Error received status: %d from %s, with message: %s", e.StatusCode, e.URL, msg)
+}
+
+// Is allows matching an APIError against the sentinel errors with errors.Is
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServerError:
+		return e.StatusCode >= 500 && e.StatusCode <= 599
+	}
+	return false
+}
+
+// MissingDataError is returned when a successful response has no "data" payload
+type MissingDataError struct {
+	URL string // URL of the request
+}
+
+// Error implements error
+func (e *MissingDataError) Error() string {
+	return fmt.Sprintf(`Response from %s is missing "data" payload`, e.URL)
+}
+
+// MissingTimestampError is returned when a successful response has no "timestamp"
+type MissingTimestampError struct {
+	URL string // URL of the request
+}
+
+// Error implements error
+func (e *MissingTimestampError) Error() string {
+	return fmt.Sprintf(`Response from %s is missing required "timestamp"`, e.URL)
+}
+
+// DecodeError is returned when a response does not match the schema expected for an endpoint
+type DecodeError struct {
+	Endpoint string // api path template of the call, e.g. "/assets/{id}"
+	Offset   int64  // byte offset of the failure within the decoded json, -1 if unknown
+	Err      error  // underlying error from encoding/json
+}
+
+// newDecodeError wraps an encoding/json error, extracting the offset where possible
+func newDecodeError(endpoint string, err error) *DecodeError {
+	decodeErr := &DecodeError{Endpoint: endpoint, Offset: -1, Err: err}
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) {
+		decodeErr.Offset = syntaxErr.Offset
+	} else if errors.As(err, &typeErr) {
+		decodeErr.Offset = typeErr.Offset
+	}
+	return decodeErr
+}
+
+// Error implements error
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("Error decoding %s response at byte %d: %v", e.Endpoint, e.Offset, e.Err)
+}
+
+// Unwrap returns the underlying encoding/json error
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// redactedError replaces the message of an error that contained sensitive data
+type redactedError struct {
+	msg string
+	err error
+}
+
+// Error implements error
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+// Unwrap allows errors.Is and errors.As to inspect the original error
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,136 @@
+package coincap
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestAPIErrorStatusCodes(t *testing.T) {
+	tests := []struct {
+		status   int
+		sentinel error
+	}{
+		{http.StatusBadRequest, ErrBadRequest},
+		{http.StatusUnauthorized, ErrUnauthorized},
+		{http.StatusForbidden, ErrUnauthorized},
+		{http.StatusNotFound, ErrNotFound},
+		{http.StatusTooManyRequests, ErrRateLimited},
+		{http.StatusBadGateway, ErrServerError},
+	}
+
+	for _, tt := range tests {
+		teardown := setup()
+
+		status := tt.status
+		r.HandleFunc("/assets/{id}", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("X-Test", "present")
+			w.WriteHeader(status)
+			fmt.Fprint(w, `{"error":"bitcoin2 not found","timestamp":1536336916333}`)
+		})
+
+		_, _, err := client.AssetByID("bitcoin2")
+		if !errors.Is(err, tt.sentinel) {
+			t.Errorf("Status %d: expected error to match %v but got: %v", tt.status, tt.sentinel, err)
+		}
+		if tt.sentinel != ErrNotFound && errors.Is(err, ErrNotFound) {
+			t.Errorf("Status %d: did not expect error to match ErrNotFound", tt.status)
+		}
+
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) {
+			t.Fatalf("Expected an *APIError but got %T", err)
+		}
+		if apiErr.StatusCode != tt.status {
+			t.Errorf("Expected status code %d but was %d", tt.status, apiErr.StatusCode)
+		}
+		if apiErr.Message != "bitcoin2 not found" {
+			t.Errorf("Expected message to be decoded from body but was: %s", apiErr.Message)
+		}
+		if apiErr.URL != server.URL+"/assets/bitcoin2" {
+			t.Errorf("Expected request URL to be recorded but was: %s", apiErr.URL)
+		}
+		if apiErr.Header.Get("X-Test") != "present" {
+			t.Errorf("Expected response headers to be recorded")
+		}
+
+		teardown()
+	}
+}
+
+func TestAPIErrorNonJSONBody(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	r.HandleFunc("/rates", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, "upstream unavailable")
+	})
+
+	_, _, err := client.Rates()
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected an *APIError but got %T", err)
+	}
+	if apiErr.Message != "" || apiErr.Body != "upstream unavailable" {
+		t.Errorf("Expected raw body to be kept when no message is present, got %+v", apiErr)
+	}
+}
+
+func TestMissingDataError(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	r.HandleFunc("/rates", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, `{"timestamp":1536336916333}`)
+	})
+
+	_, _, err := client.Rates()
+	var missing *MissingDataError
+	if !errors.As(err, &missing) {
+		t.Errorf("Expected a *MissingDataError but got %T: %v", err, err)
+	}
+}
+
+func TestMissingTimestampError(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	r.HandleFunc("/rates", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, `{"data":[]}`)
+	})
+
+	_, _, err := client.Rates()
+	var missing *MissingTimestampError
+	if !errors.As(err, &missing) {
+		t.Errorf("Expected a *MissingTimestampError but got %T: %v", err, err)
+	}
+}
+
+// serveFixture registers a handler responding to route with the given fixture
+func serveFixture(route, name string) {
+	r.HandleFunc(route, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, fixture(name))
+	})
+}
+
+// assertDecodeError fails the test unless err is a *DecodeError for endpoint with a known offset
+func assertDecodeError(t *testing.T, err error, endpoint string) {
+	t.Helper()
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("Expected a *DecodeError but got %T: %v", err, err)
+	}
+	if decodeErr.Endpoint != endpoint {
+		t.Errorf("Expected decode error for %s but was for %s", endpoint, decodeErr.Endpoint)
+	}
+	if decodeErr.Offset <= 0 {
+		t.Errorf("Expected the byte offset of the failure but got %d", decodeErr.Offset)
+	}
+}
@@ -1,41 +1,70 @@
 package coincap
 
 import (
-	"encoding/json"
+	"context"
 	"net/http"
 )
 
 // Exchange contains information about a cryptocurrency exchange. This includes the exchanges
 // relative rank, volume, and whether trading sockets are available
 type Exchange struct {
-	ID                 string    `json:"id"`                 // unique identifier for exchange
-	Name               string    `json:"name"`               // proper name of exchange
-	Rank               string    `json:"rank"`               // rank in terms of total volume compared to other exchanges
-	PercentTotalVolume string    `json:"percentTotalVolume"` // perecent of total daily volume in relation to all exchanges
-	VolumeUSD          string    `json:"volumeUSD"`          // daily volume represented in USD
-	TradingPairs       string    `json:"tradingPairs"`       // number of trading pairs offered by the exchange
-	Socket             bool      `json:"socket"`             // Whether or not a trade socket is available on this exchange
-	Updated            Timestamp `json:"updated"`            // Time since information was last updated
+	ID                 string     `json:"id"`                 // unique identifier for exchange
+	Name               string     `json:"name"`               // proper name of exchange
+	Rank               string     `json:"rank"`               // rank in terms of total volume compared to other exchanges
+	PercentTotalVolume NullString `json:"percentTotalVolume"` // perecent of total daily volume in relation to all exchanges
+	VolumeUSD          NullString `json:"volumeUSD"`          // daily volume represented in USD
+	TradingPairs       string     `json:"tradingPairs"`       // number of trading pairs offered by the exchange
+	Socket             bool       `json:"socket"`             // Whether or not a trade socket is available on this exchange
+	ExchangeURL        NullString `json:"exchangeUrl"`        // website of the exchange
+	Updated            Timestamp  `json:"updated"`            // Time since information was last updated
+}
+
+// RankDecimal parses Rank as an exact Decimal
+func (e *Exchange) RankDecimal() (Decimal, error) {
+	return parseDecimalField("rank", e.Rank)
+}
+
+// PercentTotalVolumeDecimal parses PercentTotalVolume as an exact Decimal
+func (e *Exchange) PercentTotalVolumeDecimal() (Decimal, error) {
+	return parseDecimalField("percentTotalVolume", e.PercentTotalVolume.String)
+}
+
+// VolumeUSDDecimal parses VolumeUSD as an exact Decimal
+func (e *Exchange) VolumeUSDDecimal() (Decimal, error) {
+	return parseDecimalField("volumeUsd", e.VolumeUSD.String)
+}
+
+// TradingPairsDecimal parses TradingPairs as an exact Decimal
+func (e *Exchange) TradingPairsDecimal() (Decimal, error) {
+	return parseDecimalField("tradingPairs", e.TradingPairs)
 }
 
 // Exchanges returns information about all the various exchanges currently tracked by CoinCap.
 // GET /exchanges
 func (c *Client) Exchanges() ([]*Exchange, *Timestamp, error) {
+	return c.ExchangesWithContext(context.Background())
+}
+
+// ExchangesWithContext is like Exchanges but carries ctx through the request,
+// allowing the caller to set deadlines or cancel the call
+func (c *Client) ExchangesWithContext(ctx context.Context) ([]*Exchange, *Timestamp, error) {
 
-	req, err := http.NewRequest("GET", c.baseURL+"/exchanges", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/exchanges", nil)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	// make the request
-	ccResp, err := c.fetchAndParse(req)
+	ccResp, err := c.fetchAndParse("/exchanges", req)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	// Unmarshal the deferred json from the data field
 	var exchanges []*Exchange
-	json.Unmarshal(*ccResp.Data, &exchanges)
+	if err := decodeData("/exchanges", ccResp, &exchanges); err != nil {
+		return nil, nil, err
+	}
 
 	return exchanges, ccResp.Timestamp, nil
 }
@@ -43,21 +72,29 @@ func (c *Client) Exchanges() ([]*Exchange, *Timestamp, error) {
 // ExchangeByID returns exchange data for an exchange with the given unique ID.
 // GET /exchanges/{{id}}
 func (c *Client) ExchangeByID(id string) (*Exchange, *Timestamp, error) {
+	return c.ExchangeByIDWithContext(context.Background(), id)
+}
+
+// ExchangeByIDWithContext is like ExchangeByID but carries ctx through the request,
+// allowing the caller to set deadlines or cancel the call
+func (c *Client) ExchangeByIDWithContext(ctx context.Context, id string) (*Exchange, *Timestamp, error) {
 
-	req, err := http.NewRequest("GET", c.baseURL+"/exchanges/"+id, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/exchanges/"+id, nil)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	// make the request
-	ccResp, err := c.fetchAndParse(req)
+	ccResp, err := c.fetchAndParse("/exchanges/{id}", req)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	// Unmarshal the deferred json from the data field
 	var exchange *Exchange
-	json.Unmarshal(*ccResp.Data, &exchange)
+	if err := decodeData("/exchanges/{id}", ccResp, &exchange); err != nil {
+		return nil, nil, err
+	}
 
 	return exchange, ccResp.Timestamp, nil
 }
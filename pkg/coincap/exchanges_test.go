@@ -34,8 +34,8 @@ func TestExchanges(t *testing.T) {
 		ID:                 "binance",
 		Name:               "Binance",
 		Rank:               "1",
-		PercentTotalVolume: "16.903027981466749702000000000000000000",
-		VolumeUSD:          "1034850514.6425770861221546",
+		PercentTotalVolume: NewNullString("16.903027981466749702000000000000000000"),
+		VolumeUSD:          NewNullString("1034850514.6425770861221546"),
 		TradingPairs:       "375",
 		Socket:             true,
 		Updated:            ts,
@@ -83,3 +83,21 @@ func TestExchangeByID(t *testing.T) {
 	}
 
 }
+
+func TestExchangesMalformed(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+	serveFixture("/exchanges", "exchange_malformed.json")
+
+	_, _, err := client.Exchanges()
+	assertDecodeError(t, err, "/exchanges")
+}
+
+func TestExchangeByIDMalformed(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+	serveFixture("/exchanges/{id}", "exchangeByID_malformed.json")
+
+	_, _, err := client.ExchangeByID("gdax")
+	assertDecodeError(t, err, "/exchanges/{id}")
+}
@@ -2,4 +2,7 @@ module github.com/solipsis/coincapV2/pkg/coincap
 
 go 1.13
 
-require github.com/gorilla/mux v1.7.3
+require (
+	github.com/gorilla/mux v1.7.3
+	github.com/gorilla/websocket v1.4.1
+)
@@ -0,0 +1,80 @@
+package coincap
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Call describes a call to an endpoint of the API as seen by an Interceptor
+type Call struct {
+	Endpoint string        // api path template, e.g. "/assets/{id}/history"
+	Request  *http.Request // request about to be sent. Interceptors may modify or replace it before calling next
+}
+
+// PathParams returns the values of the endpoint's path parameters, e.g. "id", in the current Request
+func (c *Call) PathParams() map[string]string {
+	return pathParams(c.Endpoint, c.Request.URL)
+}
+
+// Query returns the query parameters of the current Request, including any left empty
+func (c *Call) Query() url.Values {
+	return c.Request.URL.Query()
+}
+
+// CallResponse is the outcome of a Call before its body is parsed
+type CallResponse struct {
+	Response *http.Response // status and headers of the final response. Its body has been read into Body
+	Body     []byte         // response body, already decompressed
+}
+
+// CallHandler makes a call and returns its response, whatever the status code,
+// or the error that prevented getting one
+type CallHandler func(call *Call) (*CallResponse, error)
+
+// Interceptor wraps every call made by the client. It may inspect or modify the call before
+// passing it on to next and inspect or replace the response, or answer the call itself without
+// calling next, e.g. from a cache. Unlike Middleware, which sees every HTTP request, an
+// interceptor sees each call once however often it is retried and knows which endpoint
+// was called with which parameters
+type Interceptor func(call *Call, next CallHandler) (*CallResponse, error)
+
+// intercept passes call through the client's interceptors, outermost first, to handler
+func (c *Client) intercept(call *Call, handler CallHandler) (*CallResponse, error) {
+	for i := len(c.interceptors) - 1; i >= 0; i-- {
+		interceptor, next := c.interceptors[i], handler
+		handler = func(call *Call) (*CallResponse, error) {
+			return interceptor(call, next)
+		}
+	}
+	res, err := handler(call)
+	if err == nil && (res == nil || res.Response == nil) {
+		return nil, fmt.Errorf("Interceptor returned no response for %s", call.Endpoint)
+	}
+	return res, err
+}
+
+// pathParams returns the values of the path parameters named by endpoint, e.g. "id" for "/assets/{id}"
+func pathParams(endpoint string, u *url.URL) map[string]string {
+	params := make(map[string]string)
+
+	// the path ends with the endpoint, following the path of the base url
+	template := strings.Split(strings.Trim(endpoint, "/"), "/")
+	path := strings.Split(strings.Trim(u.EscapedPath(), "/"), "/")
+	if len(path) < len(template) {
+		return params
+	}
+	path = path[len(path)-len(template):]
+	for i, segment := range template {
+		if !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}") {
+			continue
+		}
+		value, err := url.PathUnescape(path[i])
+		if err != nil {
+			value = path[i]
+		}
+		params[segment[1:len(segment)-1]] = value
+	}
+	return params
+}
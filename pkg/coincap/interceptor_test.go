@@ -0,0 +1,215 @@
+package coincap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInterceptors(t *testing.T) {
+	var order []string
+	var calls []*Call
+	tag := func(name string) Interceptor {
+		return func(call *Call, next CallHandler) (*CallResponse, error) {
+			order = append(order, name)
+			call.Request.Header.Set("X-"+name, "true")
+			res, err := next(call)
+			order = append(order, name+" done")
+			return res, err
+		}
+	}
+	record := func(call *Call, next CallHandler) (*CallResponse, error) {
+		calls = append(calls, call)
+		return next(call)
+	}
+	teardown := setup(WithInterceptors(tag("Outer"), tag("Inner"), record))
+	defer teardown()
+
+	r.HandleFunc("/assets/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Outer") != "true" || r.Header.Get("X-Inner") != "true" {
+			t.Errorf("Expected both interceptors to modify the request")
+		}
+		fmt.Fprint(w, fixture("assetByID.json"))
+	})
+	serveFixture("/assets", "assets.json")
+
+	if _, _, err := client.AssetByID("bitcoin"); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(order, ","); got != "Outer,Inner,Inner done,Outer done" {
+		t.Errorf("Expected interceptors to run outermost first but ran %s", got)
+	}
+	if _, _, err := client.Assets(&AssetsRequest{IDs: []string{"bitcoin", "ethereum"}, Limit: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []struct {
+		endpoint string
+		path     map[string]string
+		query    url.Values
+	}{
+		{"/assets/{id}", map[string]string{"id": "bitcoin"}, url.Values{}},
+		{"/assets", map[string]string{}, url.Values{"search": {""}, "ids": {"bitcoin,ethereum"}, "limit": {"2"}}},
+	}
+	if len(calls) != len(expected) {
+		t.Fatalf("Expected %d calls but got %d", len(expected), len(calls))
+	}
+	for i, e := range expected {
+		c := calls[i]
+		if c.Endpoint != e.endpoint || !reflect.DeepEqual(c.PathParams(), e.path) || !reflect.DeepEqual(c.Query(), e.query) {
+			t.Errorf("Expected %s with %v and %v but got %s with %v and %v", e.endpoint, e.path, e.query, c.Endpoint, c.PathParams(), c.Query())
+		}
+	}
+}
+
+func TestInterceptorRewritesCall(t *testing.T) {
+	var seen url.Values
+	rewrite := func(call *Call, next CallHandler) (*CallResponse, error) {
+		u := *call.Request.URL
+		q := u.Query()
+		q.Set("limit", "5")
+		u.RawQuery = q.Encode()
+		req := call.Request.Clone(call.Request.Context())
+		req.URL = &u
+		call.Request = req
+		return next(call)
+	}
+	inspect := func(call *Call, next CallHandler) (*CallResponse, error) {
+		seen = call.Query()
+		return next(call)
+	}
+	teardown := setup(WithInterceptors(rewrite, inspect))
+	defer teardown()
+
+	var sent string
+	r.HandleFunc("/assets", func(w http.ResponseWriter, r *http.Request) {
+		sent = r.URL.Query().Get("limit")
+		fmt.Fprint(w, fixture("assets.json"))
+	})
+
+	if _, _, err := client.Assets(&AssetsRequest{Limit: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if sent != "5" || seen.Get("limit") != "5" {
+		t.Errorf("Expected the rewritten limit to be sent and seen but sent %q and saw %v", sent, seen)
+	}
+}
+
+func TestInterceptorSeesCallOnce(t *testing.T) {
+	var statuses []int
+	observe := func(call *Call, next CallHandler) (*CallResponse, error) {
+		res, err := next(call)
+		if err == nil {
+			statuses = append(statuses, res.Response.StatusCode)
+		}
+		return res, err
+	}
+	teardown := setup(WithRetryPolicy(&RetryPolicy{MaxAttempts: 3}), WithInterceptors(observe))
+	defer teardown()
+	client.clock = newFakeClock()
+
+	handler, _ := failingHandler("rates.json", nil, http.StatusBadGateway)
+	r.HandleFunc("/rates", handler)
+	r.HandleFunc("/rates/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":"rate not found"}`)
+	})
+
+	if _, _, err := client.Rates(); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := client.RateByID("nope"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the failed call to be reported but got %v", err)
+	}
+	if !reflect.DeepEqual(statuses, []int{http.StatusOK, http.StatusNotFound}) {
+		t.Errorf("Expected each call to be seen once with its final status but got %v", statuses)
+	}
+}
+
+func TestInterceptorCache(t *testing.T) {
+	cache := make(map[string]*CallResponse)
+	caching := func(call *Call, next CallHandler) (*CallResponse, error) {
+		key := call.Request.URL.String()
+		if res, ok := cache[key]; ok {
+			return res, nil
+		}
+		res, err := next(call)
+		if err == nil && res.Response.StatusCode == http.StatusOK {
+			cache[key] = res
+		}
+		return res, err
+	}
+	teardown := setup(WithInterceptors(caching))
+	defer teardown()
+
+	requests := 0
+	r.HandleFunc("/exchanges/{id}", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, fixture("exchangeByID.json"))
+	})
+
+	// one meta reused for both calls describes each in turn
+	var meta ResponseMeta
+	ctx := WithResponseMeta(context.Background(), &meta)
+	first, _, err := client.ExchangeByIDWithContext(ctx, "kraken")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Attempts != 1 || meta.StatusCode != http.StatusOK {
+		t.Errorf("Expected one attempt for the first call but got %+v", meta)
+	}
+	second, _, err := client.ExchangeByIDWithContext(ctx, "kraken")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests != 1 {
+		t.Errorf("Expected the second call to be answered from the cache but made %d requests", requests)
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("Expected the cached exchange %+v but got %+v", first, second)
+	}
+	if meta.Attempts != 0 || meta.StatusCode != http.StatusOK {
+		t.Errorf("Expected no attempts and the cached status but got %+v", meta)
+	}
+}
+
+func TestInterceptorErrors(t *testing.T) {
+	denied := errors.New("denied by policy")
+	tests := []struct {
+		name        string
+		interceptor Interceptor
+		check       func(err error) bool
+	}{
+		{"error", func(call *Call, next CallHandler) (*CallResponse, error) {
+			return nil, fmt.Errorf("Calling %s with secret-key: %w", call.Endpoint, denied)
+		}, func(err error) bool {
+			return errors.Is(err, denied) && !strings.Contains(err.Error(), "secret-key")
+		}},
+		{"no response", func(call *Call, next CallHandler) (*CallResponse, error) {
+			return nil, nil
+		}, func(err error) bool {
+			return err != nil
+		}},
+	}
+	for _, test := range tests {
+		teardown := setup(WithAPIKey("secret-key"), WithInterceptors(test.interceptor))
+		requested := false
+		r.HandleFunc("/markets", func(w http.ResponseWriter, r *http.Request) {
+			requested = true
+		})
+
+		_, _, err := client.Markets(&MarketsRequest{})
+		if !test.check(err) {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+		}
+		if requested {
+			t.Errorf("%s: expected the interceptor to stop the call", test.name)
+		}
+		teardown()
+	}
+}
@@ -1,5 +1,12 @@
 package coincap
 
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
 // Interval represents point-in-time intervals for retrieving historical market data
 type Interval string
 
@@ -21,3 +28,94 @@ const (
 	TwelveHours   Interval = "h12"
 	Week          Interval = "w1"
 )
+
+// intervalDurations maps each Interval to the span of time between two of its points
+var intervalDurations = map[Interval]time.Duration{
+	Minute:         time.Minute,
+	FiveMinutes:    5 * time.Minute,
+	FifteenMinutes: 15 * time.Minute,
+	ThirtyMinutes:  30 * time.Minute,
+	Hour:           time.Hour,
+	TwoHours:       2 * time.Hour,
+	FourHours:      4 * time.Hour,
+	EightHours:     8 * time.Hour,
+	TwelveHours:    12 * time.Hour,
+	Day:            24 * time.Hour,
+	Week:           7 * 24 * time.Hour,
+}
+
+// historyIntervals are the intervals accepted by the /assets/{id}/history endpoint
+var historyIntervals = map[Interval]bool{
+	Minute:         true,
+	FifteenMinutes: true,
+	Hour:           true,
+	Day:            true,
+}
+
+// ParseInterval parses an interval such as "m15" or "h1".
+// Surrounding whitespace and case are ignored
+func ParseInterval(s string) (Interval, error) {
+	i := Interval(strings.ToLower(strings.TrimSpace(s)))
+	if !i.Valid() {
+		return "", fmt.Errorf("Invalid interval %q", s)
+	}
+	return i, nil
+}
+
+// Valid reports whether i is one of the known intervals
+func (i Interval) Valid() bool {
+	_, ok := intervalDurations[i]
+	return ok
+}
+
+// ValidForAssetHistory reports whether i is accepted by the asset history endpoint (m1, m15, h1 and d1)
+func (i Interval) ValidForAssetHistory() bool {
+	return historyIntervals[i]
+}
+
+// ValidForCandles reports whether i is accepted by the candles endpoint, which accepts every known interval
+func (i Interval) ValidForCandles() bool {
+	return i.Valid()
+}
+
+// Duration returns the span of time between two points at interval i, or 0 if i is not valid
+func (i Interval) Duration() time.Duration {
+	return intervalDurations[i]
+}
+
+// Truncate returns t rounded down to the start of the interval it falls in.
+// Boundaries are in UTC, so days start at midnight UTC and weeks on Monday.
+// t is returned unchanged if i is not valid
+func (i Interval) Truncate(t time.Time) time.Time {
+	d := i.Duration()
+	if d == 0 {
+		return t
+	}
+	return t.Truncate(d)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler so intervals can be read from
+// config files and flags. An empty value leaves the interval unset so the endpoint's
+// default applies, other invalid intervals are rejected
+func (i *Interval) UnmarshalText(text []byte) error {
+	if len(bytes.TrimSpace(text)) == 0 {
+		*i = ""
+		return nil
+	}
+	parsed, err := ParseInterval(string(text))
+	if err != nil {
+		return err
+	}
+	*i = parsed
+	return nil
+}
+
+// String implements fmt.Stringer
+func (i Interval) String() string {
+	return string(i)
+}
+
+// Set implements flag.Value so an Interval can be used with flag.Var
+func (i *Interval) Set(s string) error {
+	return i.UnmarshalText([]byte(s))
+}
@@ -0,0 +1,131 @@
+package coincap
+
+import (
+	"encoding/json"
+	"flag"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseInterval(t *testing.T) {
+	tests := []struct {
+		in       string
+		interval Interval
+		duration time.Duration
+		wantErr  bool
+	}{
+		{"m1", Minute, time.Minute, false},
+		{"m5", FiveMinutes, 5 * time.Minute, false},
+		{"m15", FifteenMinutes, 15 * time.Minute, false},
+		{"m30", ThirtyMinutes, 30 * time.Minute, false},
+		{"h1", Hour, time.Hour, false},
+		{"h2", TwoHours, 2 * time.Hour, false},
+		{"h4", FourHours, 4 * time.Hour, false},
+		{"h8", EightHours, 8 * time.Hour, false},
+		{"h12", TwelveHours, 12 * time.Hour, false},
+		{"d1", Day, 24 * time.Hour, false},
+		{"w1", Week, 7 * 24 * time.Hour, false},
+		{" H1 ", Hour, time.Hour, false},
+		{"", "", 0, true},
+		{"m2", "", 0, true},
+		{"1h", "", 0, true},
+	}
+	for _, test := range tests {
+		interval, err := ParseInterval(test.in)
+		if (err != nil) != test.wantErr {
+			t.Errorf("ParseInterval(%q): unexpected error %v", test.in, err)
+			continue
+		}
+		if interval != test.interval || interval.Duration() != test.duration {
+			t.Errorf("ParseInterval(%q): expected %s (%v) but got %s (%v)", test.in, test.interval, test.duration, interval, interval.Duration())
+		}
+		if interval.Valid() == test.wantErr {
+			t.Errorf("ParseInterval(%q): expected Valid to be %v", test.in, !test.wantErr)
+		}
+	}
+}
+
+func TestIntervalEndpointValidity(t *testing.T) {
+	for _, i := range []Interval{Minute, FifteenMinutes, Hour, Day} {
+		if !i.ValidForAssetHistory() || !i.ValidForCandles() {
+			t.Errorf("Expected %s to be valid for asset history and candles", i)
+		}
+	}
+	for _, i := range []Interval{FiveMinutes, ThirtyMinutes, TwoHours, FourHours, EightHours, TwelveHours, Week} {
+		if i.ValidForAssetHistory() || !i.ValidForCandles() {
+			t.Errorf("Expected %s to be valid for candles only", i)
+		}
+	}
+	if Interval("m2").ValidForCandles() || Interval("").ValidForAssetHistory() {
+		t.Errorf("Expected invalid intervals to be rejected")
+	}
+}
+
+func TestIntervalTruncate(t *testing.T) {
+	tm := time.Date(2019, 6, 13, 17, 47, 12, 500, time.UTC) // a Thursday
+	tests := []struct {
+		interval Interval
+		want     time.Time
+	}{
+		{Minute, time.Date(2019, 6, 13, 17, 47, 0, 0, time.UTC)},
+		{FifteenMinutes, time.Date(2019, 6, 13, 17, 45, 0, 0, time.UTC)},
+		{FourHours, time.Date(2019, 6, 13, 16, 0, 0, 0, time.UTC)},
+		{Day, time.Date(2019, 6, 13, 0, 0, 0, 0, time.UTC)},
+		{Week, time.Date(2019, 6, 10, 0, 0, 0, 0, time.UTC)},
+		{"bad", tm},
+	}
+	for _, test := range tests {
+		if got := test.interval.Truncate(tm); !got.Equal(test.want) {
+			t.Errorf("%s: expected %v but got %v", test.interval, test.want, got)
+		}
+	}
+}
+
+func TestIntervalUnmarshalText(t *testing.T) {
+	var config struct {
+		Interval Interval `json:"interval"`
+	}
+	if err := json.Unmarshal([]byte(`{"interval":"h12"}`), &config); err != nil {
+		t.Fatal(err)
+	}
+	if config.Interval != TwelveHours {
+		t.Errorf("Expected h12 but got %s", config.Interval)
+	}
+	if err := json.Unmarshal([]byte(`{"interval":"h3"}`), &config); err == nil {
+		t.Errorf("Expected an invalid interval to be rejected")
+	}
+	if err := json.Unmarshal([]byte(`{"interval":""}`), &config); err != nil {
+		t.Errorf("Expected an empty interval to be accepted but got %v", err)
+	}
+	if config.Interval != "" {
+		t.Errorf("Expected an empty interval to leave it unset but got %s", config.Interval)
+	}
+
+	var interval Interval
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&interval, "interval", "candle interval")
+	if err := fs.Parse([]string{"-interval", "w1"}); err != nil {
+		t.Fatal(err)
+	}
+	if interval != Week {
+		t.Errorf("Expected w1 but got %s", interval)
+	}
+}
+
+func TestIntervalEnforced(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	r.PathPrefix("/").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Expected no request for an unsupported interval but got %s", r.URL)
+	})
+
+	if _, _, err := client.AssetHistoryByID("bitcoin", &AssetHistoryRequest{Interval: Week}); err == nil {
+		t.Errorf("Expected asset history to reject w1")
+	}
+	req := CandlesRequest{ExchangeID: "poloniex", BaseID: "ethereum", QuoteID: "bitcoin", Interval: "m2"}
+	if _, _, err := client.Candles(&req); err == nil {
+		t.Errorf("Expected candles to reject m2")
+	}
+}
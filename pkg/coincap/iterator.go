@@ -0,0 +1,266 @@
+package coincap
+
+import (
+	"context"
+)
+
+// maxPageSize is the largest number of entries the API returns per request
+const maxPageSize = 2000
+
+// IteratorOptions configures how an iterator walks the pages of an endpoint
+type IteratorOptions struct {
+	PageSize int // entries requested per page. Defaults to and is capped at 2000
+	MaxItems int // stop after yielding this many entries. 0 means no limit
+}
+
+// pager holds the paging state shared by the iterators. Consecutive pages overlap
+// by one entry so that entries shifting between pages while iterating, e.g. because
+// rankings changed, can be detected and duplicates skipped
+type pager struct {
+	ctx      context.Context
+	pageSize int
+	maxItems int
+
+	offset     int             // offset of the next unseen entry
+	lastKey    string          // key of the last entry of the previous page
+	seen       map[string]bool // keys of every entry yielded so far
+	count      int             // number of entries yielded so far
+	duplicates int             // entries skipped because they were already yielded
+	shifts     int             // pages whose overlap did not match the previous page
+	done       bool
+	err        error
+}
+
+func newPager(ctx context.Context, offset int, opts *IteratorOptions) *pager {
+	p := &pager{
+		ctx:      ctx,
+		pageSize: maxPageSize,
+		offset:   offset,
+		seen:     make(map[string]bool),
+	}
+	if opts != nil {
+		if opts.PageSize > 0 && opts.PageSize < maxPageSize {
+			p.pageSize = opts.PageSize
+		}
+		p.maxItems = opts.MaxItems
+	}
+	// a page of one entry would be nothing but overlap
+	if p.pageSize < 2 {
+		p.pageSize = 2
+	}
+	return p
+}
+
+// nextPage fetches the next page with fetch, which returns the key of every entry on the page,
+// and returns the positions of the entries that have not been yielded before
+func (p *pager) nextPage(fetch func(offset, limit int) ([]string, error)) []int {
+	if p.done {
+		return nil
+	}
+	if err := p.ctx.Err(); err != nil {
+		p.fail(err)
+		return nil
+	}
+
+	// re-request the last entry of the previous page to verify nothing moved
+	offset, prevKey := p.offset, p.lastKey
+	overlap := 0
+	if prevKey != "" {
+		offset, overlap = p.offset-1, 1
+	}
+	keys, err := fetch(offset, p.pageSize)
+	if err != nil {
+		p.fail(err)
+		return nil
+	}
+	if len(keys) < p.pageSize {
+		p.done = true
+	}
+	if overlap == 1 && (len(keys) == 0 || keys[0] != prevKey) {
+		p.shifts++
+	}
+	if len(keys) > overlap {
+		p.offset += len(keys) - overlap
+		p.lastKey = keys[len(keys)-1]
+	} else {
+		p.done = true
+	}
+
+	fresh := make([]int, 0, len(keys))
+	for i, key := range keys {
+		if p.seen[key] {
+			// only the overlapping entry is expected to repeat
+			if i > 0 || key != prevKey {
+				p.duplicates++
+			}
+			continue
+		}
+		p.seen[key] = true
+		fresh = append(fresh, i)
+	}
+	return fresh
+}
+
+// full reports whether MaxItems entries have been yielded
+func (p *pager) full() bool {
+	return p.maxItems > 0 && p.count >= p.maxItems
+}
+
+func (p *pager) fail(err error) {
+	p.err = err
+	p.done = true
+}
+
+// AssetsIterator walks every page of the /assets endpoint. Use it like
+//
+//	it := client.AssetsIterator(ctx, &coincap.AssetsRequest{}, nil)
+//	for it.Next() {
+//		asset := it.Value()
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type AssetsIterator struct {
+	client  *Client
+	params  AssetsRequest
+	pager   *pager
+	pending []*Asset
+	current *Asset
+}
+
+// AssetsIterator returns an iterator over every asset matching reqParams, starting at reqParams.Offset.
+// reqParams.Limit is ignored in favor of opts.PageSize and opts.MaxItems
+func (c *Client) AssetsIterator(ctx context.Context, reqParams *AssetsRequest, opts *IteratorOptions) *AssetsIterator {
+	it := &AssetsIterator{client: c}
+	if reqParams != nil {
+		it.params = *reqParams
+	}
+	it.pager = newPager(ctx, it.params.Offset, opts)
+	return it
+}
+
+// Next advances to the next asset, fetching a new page when needed.
+// It returns false when there are no more assets or an error occurred
+func (it *AssetsIterator) Next() bool {
+	if it.pager.full() {
+		return false
+	}
+	for len(it.pending) == 0 {
+		if it.pager.done {
+			return false
+		}
+		var page []*Asset
+		fresh := it.pager.nextPage(func(offset, limit int) ([]string, error) {
+			params := it.params
+			params.Offset, params.Limit = offset, limit
+			assets, _, err := it.client.AssetsWithContext(it.pager.ctx, &params)
+			page = assets
+			keys := make([]string, len(assets))
+			for i, asset := range assets {
+				keys[i] = asset.ID
+			}
+			return keys, err
+		})
+		for _, i := range fresh {
+			it.pending = append(it.pending, page[i])
+		}
+	}
+	it.pager.count++
+	it.current, it.pending = it.pending[0], it.pending[1:]
+	return true
+}
+
+// Value returns the current asset
+func (it *AssetsIterator) Value() *Asset {
+	return it.current
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *AssetsIterator) Err() error {
+	return it.pager.err
+}
+
+// Duplicates returns the number of assets skipped because they were already returned
+// on an earlier page. This happens when rankings change while iterating
+func (it *AssetsIterator) Duplicates() int {
+	return it.pager.duplicates
+}
+
+// Shifts returns the number of pages that did not line up with the previous page,
+// meaning entries moved while iterating and some may have been missed
+func (it *AssetsIterator) Shifts() int {
+	return it.pager.shifts
+}
+
+// MarketsIterator walks every page of the /markets endpoint. It is used like AssetsIterator
+type MarketsIterator struct {
+	client  *Client
+	params  MarketsRequest
+	pager   *pager
+	pending []*Market
+	current *Market
+}
+
+// MarketsIterator returns an iterator over every market matching reqParams, starting at reqParams.Offset.
+// reqParams.Limit is ignored in favor of opts.PageSize and opts.MaxItems
+func (c *Client) MarketsIterator(ctx context.Context, reqParams *MarketsRequest, opts *IteratorOptions) *MarketsIterator {
+	it := &MarketsIterator{client: c}
+	if reqParams != nil {
+		it.params = *reqParams
+	}
+	it.pager = newPager(ctx, it.params.Offset, opts)
+	return it
+}
+
+// Next advances to the next market, fetching a new page when needed.
+// It returns false when there are no more markets or an error occurred
+func (it *MarketsIterator) Next() bool {
+	if it.pager.full() {
+		return false
+	}
+	for len(it.pending) == 0 {
+		if it.pager.done {
+			return false
+		}
+		var page []*Market
+		fresh := it.pager.nextPage(func(offset, limit int) ([]string, error) {
+			params := it.params
+			params.Offset, params.Limit = offset, limit
+			markets, _, err := it.client.MarketsWithContext(it.pager.ctx, &params)
+			page = markets
+			keys := make([]string, len(markets))
+			for i, market := range markets {
+				keys[i] = market.ExchangeID + "/" + market.BaseID + "/" + market.QuoteID
+			}
+			return keys, err
+		})
+		for _, i := range fresh {
+			it.pending = append(it.pending, page[i])
+		}
+	}
+	it.pager.count++
+	it.current, it.pending = it.pending[0], it.pending[1:]
+	return true
+}
+
+// Value returns the current market
+func (it *MarketsIterator) Value() *Market {
+	return it.current
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *MarketsIterator) Err() error {
+	return it.pager.err
+}
+
+// Duplicates returns the number of markets skipped because they were already returned
+// on an earlier page. This happens when rankings change while iterating
+func (it *MarketsIterator) Duplicates() int {
+	return it.pager.duplicates
+}
+
+// Shifts returns the number of pages that did not line up with the previous page,
+// meaning entries moved while iterating and some may have been missed
+func (it *MarketsIterator) Shifts() int {
+	return it.pager.shifts
+}
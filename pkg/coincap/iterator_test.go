@@ -0,0 +1,182 @@
+package coincap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// pagedAssets serves ids through /assets honoring limit and offset.
+// ids may be changed between requests to simulate ranking changes
+func pagedAssets(ids *[]string, requests *int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+		var data []string
+		for i := offset; i < len(*ids) && i < offset+limit; i++ {
+			data = append(data, fmt.Sprintf(`{"id":%q}`, (*ids)[i]))
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `{"data":[%s],"timestamp":1536336916333}`, strings.Join(data, ","))
+	}
+}
+
+func makeIDs(prefix string, n int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("%s-%d", prefix, i)
+	}
+	return ids
+}
+
+func TestAssetsIterator(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	ids := makeIDs("asset", 35)
+	requests := 0
+	r.HandleFunc("/assets", pagedAssets(&ids, &requests))
+
+	it := client.AssetsIterator(context.Background(), &AssetsRequest{}, &IteratorOptions{PageSize: 10})
+	var got []string
+	for it.Next() {
+		got = append(got, it.Value().ID)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, ",") != strings.Join(ids, ",") {
+		t.Errorf("Expected every asset exactly once in order but got %v", got)
+	}
+	if it.Duplicates() != 0 || it.Shifts() != 0 {
+		t.Errorf("Expected no duplicates or shifts, got %d and %d", it.Duplicates(), it.Shifts())
+	}
+	// pages overlap by one entry: 10 + 9 + 9 + 9 entries
+	if requests != 4 {
+		t.Errorf("Expected 4 requests but made %d", requests)
+	}
+}
+
+func TestAssetsIteratorMaxItems(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	ids := makeIDs("asset", 100)
+	requests := 0
+	r.HandleFunc("/assets", pagedAssets(&ids, &requests))
+
+	it := client.AssetsIterator(context.Background(), nil, &IteratorOptions{PageSize: 10, MaxItems: 10})
+	count := 0
+	for it.Next() {
+		count++
+	}
+	if count != 10 {
+		t.Errorf("Expected iteration to stop after 10 assets but got %d", count)
+	}
+	if requests != 1 {
+		t.Errorf("Expected no more pages to be fetched after the cap but made %d requests", requests)
+	}
+}
+
+func TestAssetsIteratorRankingShift(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	ids := makeIDs("asset", 20)
+	requests := 0
+	handler := pagedAssets(&ids, &requests)
+	r.HandleFunc("/assets", func(w http.ResponseWriter, r *http.Request) {
+		// a new asset enters the top of the rankings after the first page
+		if requests == 1 {
+			ids = append([]string{"newcomer"}, ids...)
+		}
+		handler(w, r)
+	})
+
+	it := client.AssetsIterator(context.Background(), nil, &IteratorOptions{PageSize: 10})
+	seen := map[string]int{}
+	for it.Next() {
+		seen[it.Value().ID]++
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	for id, n := range seen {
+		if n > 1 {
+			t.Errorf("Expected %s to be returned once but was returned %d times", id, n)
+		}
+	}
+	if len(seen) != 20 {
+		t.Errorf("Expected all 20 original assets to be returned but got %d", len(seen))
+	}
+	if it.Shifts() == 0 || it.Duplicates() == 0 {
+		t.Errorf("Expected the ranking change to be detected, got %d shifts and %d duplicates", it.Shifts(), it.Duplicates())
+	}
+}
+
+func TestAssetsIteratorContextCanceled(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	ids := makeIDs("asset", 100)
+	requests := 0
+	r.HandleFunc("/assets", pagedAssets(&ids, &requests))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	it := client.AssetsIterator(ctx, nil, &IteratorOptions{PageSize: 10})
+	count := 0
+	for it.Next() {
+		count++
+		if count == 5 {
+			cancel()
+		}
+	}
+	if !errors.Is(it.Err(), context.Canceled) {
+		t.Errorf("Expected iteration to stop with context canceled but got %v", it.Err())
+	}
+	if count != 10 {
+		t.Errorf("Expected the current page to be finished but got %d assets", count)
+	}
+}
+
+func TestMarketsIterator(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	r.HandleFunc("/markets", func(w http.ResponseWriter, r *http.Request) {
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		if r.URL.Query().Get("exchange") != "binance" {
+			t.Errorf("Expected request parameters to be kept")
+		}
+
+		var data []string
+		for i := offset; i < 25 && i < offset+limit; i++ {
+			data = append(data, fmt.Sprintf(`{"exchangeId":"binance","baseId":"asset-%d","quoteId":"bitcoin"}`, i))
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `{"data":[%s],"timestamp":1536336916333}`, strings.Join(data, ","))
+	})
+
+	it := client.MarketsIterator(context.Background(), &MarketsRequest{ExchangeID: "binance"}, &IteratorOptions{PageSize: 10})
+	count := 0
+	for it.Next() {
+		if expected := fmt.Sprintf("asset-%d", count); it.Value().BaseID != expected {
+			t.Errorf("Expected market %s but got %s", expected, it.Value().BaseID)
+		}
+		count++
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if count != 25 {
+		t.Errorf("Expected 25 markets but got %d", count)
+	}
+}
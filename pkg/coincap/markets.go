@@ -1,7 +1,7 @@
 package coincap
 
 import (
-	"encoding/json"
+	"context"
 	"net/http"
 	"strconv"
 )
@@ -21,27 +21,63 @@ type MarketsRequest struct {
 
 // Market contains the market data response from the api
 type Market struct {
-	ExchangeID            string    `json:"exchangeId"`            // unique identifier for exchange
-	Rank                  string    `json:"rank"`                  // rank in terms of volume transacted in this market
-	BaseSymbol            string    `json:"baseSymbol"`            // most common symbol used to identify this asset
-	BaseID                string    `json:"baseId"`                // unique identifier for this asset. base is the asset purchased
-	QuoteSymbol           string    `json:"quoteSymbol"`           // most common symbol used to identify this asset
-	QuoteID               string    `json:"quoteId"`               // unique identifier for thisasset. quote is the asset used to purchase base
-	PriceQuote            string    `json:"priceQuote"`            // amount of quote asset traded for 1 unit of base asset
-	PriceUsd              string    `json:"priceUsd"`              // quote price translated to USD
-	VolumeUsd24Hr         string    `json:"volumeUsd24Hr"`         // volume transacted in this market in the last 24 hours
-	PercentExchangeVolume string    `json:"percentExchangeVolume"` // amount of daily volume this market transacts compared to others on this exchange
-	TradesCount24Hr       string    `json:"tradesCount24Hr"`       // number of trades on this market in the last 24 hours
-	Updated               Timestamp `json:"updated"`               // last time information was received from this market
+	ExchangeID            string     `json:"exchangeId"`            // unique identifier for exchange
+	Rank                  string     `json:"rank"`                  // rank in terms of volume transacted in this market
+	BaseSymbol            string     `json:"baseSymbol"`            // most common symbol used to identify this asset
+	BaseID                string     `json:"baseId"`                // unique identifier for this asset. base is the asset purchased
+	QuoteSymbol           string     `json:"quoteSymbol"`           // most common symbol used to identify this asset
+	QuoteID               string     `json:"quoteId"`               // unique identifier for thisasset. quote is the asset used to purchase base
+	PriceQuote            string     `json:"priceQuote"`            // amount of quote asset traded for 1 unit of base asset
+	PriceUsd              NullString `json:"priceUsd"`              // quote price translated to USD
+	VolumeUsd24Hr         NullString `json:"volumeUsd24Hr"`         // volume transacted in this market in the last 24 hours
+	PercentExchangeVolume NullString `json:"percentExchangeVolume"` // amount of daily volume this market transacts compared to others on this exchange
+	TradesCount24Hr       NullString `json:"tradesCount24Hr"`       // number of trades on this market in the last 24 hours
+	Updated               Timestamp  `json:"updated"`               // last time information was received from this market
+}
+
+// RankDecimal parses Rank as an exact Decimal
+func (m *Market) RankDecimal() (Decimal, error) {
+	return parseDecimalField("rank", m.Rank)
+}
+
+// PriceQuoteDecimal parses PriceQuote as an exact Decimal
+func (m *Market) PriceQuoteDecimal() (Decimal, error) {
+	return parseDecimalField("priceQuote", m.PriceQuote)
+}
+
+// PriceUsdDecimal parses PriceUsd as an exact Decimal
+func (m *Market) PriceUsdDecimal() (Decimal, error) {
+	return parseDecimalField("priceUsd", m.PriceUsd.String)
+}
+
+// VolumeUsd24HrDecimal parses VolumeUsd24Hr as an exact Decimal
+func (m *Market) VolumeUsd24HrDecimal() (Decimal, error) {
+	return parseDecimalField("volumeUsd24Hr", m.VolumeUsd24Hr.String)
+}
+
+// PercentExchangeVolumeDecimal parses PercentExchangeVolume as an exact Decimal
+func (m *Market) PercentExchangeVolumeDecimal() (Decimal, error) {
+	return parseDecimalField("percentExchangeVolume", m.PercentExchangeVolume.String)
+}
+
+// TradesCount24HrDecimal parses TradesCount24Hr as an exact Decimal
+func (m *Market) TradesCount24HrDecimal() (Decimal, error) {
+	return parseDecimalField("tradesCount24Hr", m.TradesCount24Hr.String)
 }
 
 // Markets requests market data for all markets matching the criteria set in the MarketRequest params.
 // For historical data on markets use the Candles() endpoint.
 // GET /markets
 func (c *Client) Markets(reqParams *MarketsRequest) ([]*Market, *Timestamp, error) {
+	return c.MarketsWithContext(context.Background(), reqParams)
+}
+
+// MarketsWithContext is like Markets but carries ctx through the request,
+// allowing the caller to set deadlines or cancel the call
+func (c *Client) MarketsWithContext(ctx context.Context, reqParams *MarketsRequest) ([]*Market, *Timestamp, error) {
 
 	// Prepare the query
-	req, err := http.NewRequest("GET", c.baseURL+"/markets", nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/markets", nil)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -78,14 +114,16 @@ func (c *Client) Markets(reqParams *MarketsRequest) ([]*Market, *Timestamp, erro
 	req.URL.RawQuery = params.Encode()
 
 	// make the request
-	ccResp, err := c.fetchAndParse(req)
+	ccResp, err := c.fetchAndParse("/markets", req)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	// Unmarshal the deferred json from the data field
 	var markets []*Market
-	json.Unmarshal(*ccResp.Data, &markets)
+	if err := decodeData("/markets", ccResp, &markets); err != nil {
+		return nil, nil, err
+	}
 
 	return markets, ccResp.Timestamp, nil
 }
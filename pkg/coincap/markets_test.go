@@ -56,3 +56,12 @@ func TestMarketsLive(t *testing.T) {
 		t.Errorf("No markets were returned")
 	}
 }
+
+func TestMarketsMalformed(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+	serveFixture("/markets", "markets_malformed.json")
+
+	_, _, err := client.Markets(&MarketsRequest{ExchangeID: "binance"})
+	assertDecodeError(t, err, "/markets")
+}
@@ -0,0 +1,129 @@
+package coincap
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheStatus reports whether a response was served from a cache
+type CacheStatus string
+
+// Possible cache statuses, as reported by an X-Cache header set by a
+// caching proxy, CDN or middleware in front of the API
+const (
+	CacheUnknown CacheStatus = ""     // no cache reported on the response
+	CacheHit     CacheStatus = "HIT"  // the response was served from a cache
+	CacheMiss    CacheStatus = "MISS" // the response was fetched from the origin
+)
+
+// ResponseMeta describes the HTTP exchange behind a call to the API.
+// Request it for any call by passing a context from WithResponseMeta
+type ResponseMeta struct {
+	Timestamp  *Timestamp    // server timestamp from the response body
+	StatusCode int           // HTTP status code of the final attempt
+	Latency    time.Duration // total time of the call including retries and rate limiting
+	Attempts   int           // number of requests made, more than 1 if retried
+	Gzip       bool          // whether the response body was gzip compressed
+	URL        string        // final request URL after any redirects
+	Cache      CacheStatus   // cache hit or miss if a cache is in use
+
+	RateLimitLimit     int // value of X-Ratelimit-Limit, -1 if not present
+	RateLimitRemaining int // value of X-Ratelimit-Remaining, -1 if not present
+}
+
+type responseMetaKey struct{}
+
+// WithResponseMeta returns a context that makes the WithContext endpoint methods
+// fill meta with details of the response when the call returns, e.g.
+//
+//	var meta coincap.ResponseMeta
+//	assets, _, err := client.AssetsWithContext(coincap.WithResponseMeta(ctx, &meta), params)
+//	log.Println(meta.StatusCode, meta.Latency, meta.RateLimitRemaining)
+//
+// meta is filled in as far as possible even if the call fails. A meta belongs to one call
+// at a time and must not be shared by concurrent calls. Calls made up of several requests,
+// such as AssetsByIDs and AssetHistoryRange, combine them into meta themselves
+func WithResponseMeta(ctx context.Context, meta *ResponseMeta) context.Context {
+	return context.WithValue(ctx, responseMetaKey{}, meta)
+}
+
+// responseMetaFromContext returns the ResponseMeta requested by the caller or nil
+func responseMetaFromContext(ctx context.Context) *ResponseMeta {
+	meta, _ := ctx.Value(responseMetaKey{}).(*ResponseMeta)
+	return meta
+}
+
+// metaCombiner fills the ResponseMeta of a call made up of several requests
+type metaCombiner struct {
+	clock clock
+	start time.Time
+
+	mu   sync.Mutex
+	meta *ResponseMeta
+}
+
+// combineMeta returns a combiner for the ResponseMeta requested by ctx, or nil if none was.
+// Every request gets a meta of its own, which are combined into the caller's: attempts are
+// summed, the latency covers the whole call and the other fields describe the last response
+func (c *Client) combineMeta(ctx context.Context) *metaCombiner {
+	meta := responseMetaFromContext(ctx)
+	if meta == nil {
+		return nil
+	}
+	*meta = ResponseMeta{RateLimitLimit: -1, RateLimitRemaining: -1}
+	return &metaCombiner{clock: c.clock, start: c.clock.Now(), meta: meta}
+}
+
+// request calls fetch with a context carrying the request's own meta and combines it into
+// the caller's. It is safe for concurrent use. A nil combiner passes ctx through unchanged
+func (m *metaCombiner) request(ctx context.Context, fetch func(ctx context.Context) error) error {
+	if m == nil {
+		return fetch(ctx)
+	}
+	var meta ResponseMeta
+	err := fetch(WithResponseMeta(ctx, &meta))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	attempts := m.meta.Attempts + meta.Attempts
+	if meta.StatusCode != 0 {
+		*m.meta = meta
+	}
+	m.meta.Attempts = attempts
+	m.meta.Latency = m.clock.Now().Sub(m.start)
+	return err
+}
+
+// setResponse records the details of the final response
+func (m *ResponseMeta) setResponse(resp *http.Response) {
+	m.StatusCode = resp.StatusCode
+	m.Gzip = resp.Header.Get("Content-Encoding") == "gzip"
+	if resp.Request != nil && resp.Request.URL != nil {
+		m.URL = resp.Request.URL.String()
+	}
+
+	switch cache := strings.ToUpper(resp.Header.Get("X-Cache")); {
+	case strings.HasPrefix(cache, "HIT"):
+		m.Cache = CacheHit
+	case strings.HasPrefix(cache, "MISS"):
+		m.Cache = CacheMiss
+	default:
+		m.Cache = CacheUnknown
+	}
+
+	m.RateLimitLimit = headerInt(resp.Header, "X-Ratelimit-Limit")
+	m.RateLimitRemaining = headerInt(resp.Header, "X-Ratelimit-Remaining")
+}
+
+// headerInt parses an integer header, returning -1 if it is missing or malformed
+func headerInt(header http.Header, key string) int {
+	n, err := strconv.Atoi(header.Get(key))
+	if err != nil {
+		return -1
+	}
+	return n
+}
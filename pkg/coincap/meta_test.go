@@ -0,0 +1,85 @@
+package coincap
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestResponseMeta(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	r.HandleFunc("/assets", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("X-Ratelimit-Limit", "200")
+		w.Header().Set("X-Ratelimit-Remaining", "199")
+		w.Header().Set("X-Cache", "HIT from proxy")
+		w.WriteHeader(http.StatusOK)
+		gz := gzip.NewWriter(w)
+		fmt.Fprint(gz, fixture("assets.json"))
+		gz.Close()
+	})
+
+	var meta ResponseMeta
+	ctx := WithResponseMeta(context.Background(), &meta)
+	assets, timestamp, err := client.AssetsWithContext(ctx, &AssetsRequest{Search: "BTC"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(assets) == 0 {
+		t.Fatalf("No assets were returned")
+	}
+
+	if meta.Timestamp != timestamp {
+		t.Errorf("Expected meta timestamp to be the response timestamp")
+	}
+	if meta.StatusCode != http.StatusOK || meta.Attempts != 1 {
+		t.Errorf("Expected a single successful attempt, got %+v", meta)
+	}
+	if !meta.Gzip {
+		t.Errorf("Expected the gzip body to be reported")
+	}
+	if meta.URL != server.URL+"/assets?search=BTC" {
+		t.Errorf("Unexpected request URL %s", meta.URL)
+	}
+	if meta.RateLimitLimit != 200 || meta.RateLimitRemaining != 199 {
+		t.Errorf("Expected rate limit headers to be reported, got %+v", meta)
+	}
+	if meta.Cache != CacheHit {
+		t.Errorf("Expected a cache hit but got %q", meta.Cache)
+	}
+	if meta.Latency <= 0 {
+		t.Errorf("Expected latency to be measured")
+	}
+}
+
+func TestResponseMetaOnError(t *testing.T) {
+	teardown := setup(WithRetryPolicy(&RetryPolicy{MaxAttempts: 2}))
+	defer teardown()
+	client.clock = newFakeClock()
+
+	r.HandleFunc("/rates", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "2")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	var meta ResponseMeta
+	_, _, err := client.RatesWithContext(WithResponseMeta(context.Background(), &meta))
+	if err == nil {
+		t.Fatal("Expected the call to fail")
+	}
+	if meta.StatusCode != http.StatusTooManyRequests || meta.Attempts != 2 {
+		t.Errorf("Expected two rate limited attempts, got %+v", meta)
+	}
+	if meta.Latency != 2*time.Second {
+		t.Errorf("Expected latency to include the retry wait, got %s", meta.Latency)
+	}
+	if meta.Gzip || meta.Cache != CacheUnknown || meta.RateLimitRemaining != -1 {
+		t.Errorf("Expected absent details to be reported as unknown, got %+v", meta)
+	}
+}
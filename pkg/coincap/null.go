@@ -0,0 +1,56 @@
+package coincap
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// NullString is a string field that CoinCap may return as null or leave out entirely,
+// e.g. the maxSupply of an asset without a supply cap. It distinguishes a missing
+// field (Present is false) from a null one (Present is true, Valid is false) and from
+// an empty or zero value (Valid is true).
+//
+// It is used for the fields CoinCap is known to return as null or leave out: caps such as
+// maxSupply, 24 hour figures of assets, exchanges and markets without recent trades, shares of
+// total volume and optional metadata such as urls and currency symbols. Identifiers, ranks,
+// supplies, prices quoted by a market and conversion rates are always sent and stay plain strings
+type NullString struct {
+	String  string // the value if Valid
+	Valid   bool   // whether the field was present and not null
+	Present bool   // whether the field appeared in the response at all
+}
+
+// NewNullString returns a valid NullString holding s
+func NewNullString(s string) NullString {
+	return NullString{String: s, Valid: true, Present: true}
+}
+
+// IsNull reports whether the field was null or missing
+func (n NullString) IsNull() bool {
+	return !n.Valid
+}
+
+// MarshalJSON implements json.Marshaler. Null and missing values are both encoded as null,
+// since a field cannot leave itself out. A field absent from a decoded response is
+// therefore encoded as null and decodes again with Present set
+func (n NullString) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(n.String)
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It is only called for fields present
+// in the json so any field it is called for is marked as Present
+func (n *NullString) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(bytes.TrimSpace(b), []byte("null")) {
+		*n = NullString{Present: true}
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	*n = NewNullString(s)
+	return nil
+}
@@ -0,0 +1,76 @@
+package coincap
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNullStringStates(t *testing.T) {
+	var asset Asset
+	in := `{"id":"bitcoin","maxSupply":"21000000","vwap24Hr":null,"changePercent24Hr":""}`
+	if err := json.Unmarshal([]byte(in), &asset); err != nil {
+		t.Fatal(err)
+	}
+
+	if !asset.MaxSupply.Valid || asset.MaxSupply.String != "21000000" {
+		t.Errorf("Expected a valid max supply, got %+v", asset.MaxSupply)
+	}
+	if asset.Vwap24Hr.Valid || !asset.Vwap24Hr.Present || !asset.Vwap24Hr.IsNull() {
+		t.Errorf("Expected vwap to be present but null, got %+v", asset.Vwap24Hr)
+	}
+	if !asset.ChangePercent24Hr.Valid || asset.ChangePercent24Hr.String != "" {
+		t.Errorf("Expected an empty but valid change percent, got %+v", asset.ChangePercent24Hr)
+	}
+	if asset.Explorer.Present || asset.Explorer.Valid {
+		t.Errorf("Expected explorer to be missing, got %+v", asset.Explorer)
+	}
+}
+
+func TestNullStringRoundTrip(t *testing.T) {
+	var asset Asset
+	in := `{"id":"ethereum","maxSupply":null,"explorer":"https://etherscan.io/"}`
+	if err := json.Unmarshal([]byte(in), &asset); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := json.Marshal(asset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), `"maxSupply":null`) {
+		t.Errorf("Expected null max supply to be preserved, got %s", out)
+	}
+	if !strings.Contains(string(out), `"explorer":"https://etherscan.io/"`) {
+		t.Errorf("Expected explorer to be preserved, got %s", out)
+	}
+
+	var again Asset
+	if err := json.Unmarshal(out, &again); err != nil {
+		t.Fatal(err)
+	}
+	if again.MaxSupply != asset.MaxSupply || again.Explorer != asset.Explorer {
+		t.Errorf("Expected round trip to preserve values, got %+v", again)
+	}
+}
+
+func TestNullStringMissingEncodesAsNull(t *testing.T) {
+	var asset Asset
+	if err := json.Unmarshal([]byte(`{"id":"bitcoin"}`), &asset); err != nil {
+		t.Fatal(err)
+	}
+	out, err := json.Marshal(asset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), `"maxSupply":null`) {
+		t.Errorf("Expected a missing max supply to be encoded as null, got %s", out)
+	}
+}
+
+func TestNullStringRejectsNumbers(t *testing.T) {
+	var n NullString
+	if err := json.Unmarshal([]byte(`12`), &n); err == nil {
+		t.Errorf("Expected a number to be rejected, got %+v", n)
+	}
+}
@@ -0,0 +1,114 @@
+package coincap
+
+import (
+	"net/http"
+	"time"
+)
+
+// Option configures a Client at construction time. Options are applied in order
+// so later options override earlier ones
+type Option func(*Client)
+
+// Logger receives diagnostic messages from the client such as retried requests.
+// *log.Logger satisfies this interface
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// Middleware wraps the transport used for every request made by the client,
+// allowing requests and responses to be inspected or modified
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// WithBaseURL sets a custom api base path such as a proxy or test server
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = baseURL
+	}
+}
+
+// WithWebsocketURL sets the base URL of the realtime websocket feeds, e.g. "wss://ws.coincap.io".
+// By default it is derived from the base URL
+func WithWebsocketURL(websocketURL string) Option {
+	return func(c *Client) {
+		c.websocketURL = websocketURL
+	}
+}
+
+// WithHTTPClient sets the http.Client used to make requests. The client is not modified
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		if httpClient != nil {
+			c.httpClient = httpClient
+		}
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithAPIKey configures the CoinCap API key sent as a bearer token with every request.
+// The key is redacted from any error or log message produced by the client
+func WithAPIKey(apiKey string) Option {
+	return func(c *Client) {
+		c.apiKey = apiKey
+	}
+}
+
+// WithTimeout bounds the total time of every call, including retries and rate limiting
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = timeout
+	}
+}
+
+// WithRetryPolicy enables retrying of transient failures such as 429 and 502 responses.
+// The policy is copied so later changes to it do not affect the client
+func WithRetryPolicy(policy *RetryPolicy) Option {
+	return func(c *Client) {
+		if policy == nil {
+			c.retryPolicy = nil
+			return
+		}
+		p := *policy
+		if policy.RetryableStatusCodes != nil {
+			p.RetryableStatusCodes = make([]int, len(policy.RetryableStatusCodes))
+			copy(p.RetryableStatusCodes, policy.RetryableStatusCodes)
+		}
+		c.retryPolicy = &p
+	}
+}
+
+// WithRateLimiter makes the client wait for the limiter before every request, including retries.
+// A limiter can be shared between clients that share a quota
+func WithRateLimiter(limiter *RateLimiter) Option {
+	return func(c *Client) {
+		c.rateLimiter = limiter
+	}
+}
+
+// WithLogger sets a logger for diagnostic messages. By default nothing is logged
+func WithLogger(logger Logger) Option {
+	return func(c *Client) {
+		c.logger = logger
+	}
+}
+
+// WithMiddleware adds middleware around the client's transport. The first
+// middleware given is the outermost and sees each request first
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(c *Client) {
+		c.middleware = append(c.middleware, middleware...)
+	}
+}
+
+// WithInterceptors adds interceptors around every call made by the client. The first
+// interceptor given is the outermost and sees each call first
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(c *Client) {
+		c.interceptors = append(c.interceptors, interceptors...)
+	}
+}
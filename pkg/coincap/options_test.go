@@ -0,0 +1,166 @@
+package coincap
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// roundTripperFunc adapts a function to http.RoundTripper
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestOptionsUserAgentAndMiddleware(t *testing.T) {
+	var order []string
+	tag := func(name string) Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				order = append(order, name)
+				req.Header.Set("X-"+name, "true")
+				return next.RoundTrip(req)
+			})
+		}
+	}
+
+	httpClient := &http.Client{}
+	teardown := setup(
+		WithHTTPClient(httpClient),
+		WithUserAgent("coincap-test/1.0"),
+		WithMiddleware(tag("Outer"), tag("Inner")),
+	)
+	defer teardown()
+
+	r.HandleFunc("/rates", func(w http.ResponseWriter, r *http.Request) {
+		if ua := r.Header.Get("User-Agent"); ua != "coincap-test/1.0" {
+			t.Errorf("Expected custom user agent but got %q", ua)
+		}
+		if r.Header.Get("X-Outer") != "true" || r.Header.Get("X-Inner") != "true" {
+			t.Errorf("Expected both middleware to modify the request")
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, fixture("rates.json"))
+	})
+
+	if _, _, err := client.Rates(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(order, ",") != "Outer,Inner" {
+		t.Errorf("Expected middleware to run outermost first but ran %v", order)
+	}
+	if httpClient.Transport != nil {
+		t.Errorf("Expected the caller's http.Client to be left untouched")
+	}
+}
+
+func TestOptionsTimeout(t *testing.T) {
+	teardown := setup(WithTimeout(20 * time.Millisecond))
+	defer teardown()
+
+	r.HandleFunc("/rates", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+
+	_, _, err := client.Rates()
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the call to time out but got: %v", err)
+	}
+}
+
+func TestOptionsLoggerRedactsAPIKey(t *testing.T) {
+	// fail the first attempt with an error that leaks the key
+	calls := 0
+	leaky := func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			calls++
+			if calls == 1 {
+				return nil, errors.New("proxy rejected token secret-key")
+			}
+			return next.RoundTrip(req)
+		})
+	}
+
+	var buf bytes.Buffer
+	teardown := setup(
+		WithAPIKey("secret-key"),
+		WithLogger(log.New(&buf, "", 0)),
+		WithMiddleware(leaky),
+		WithRetryPolicy(&RetryPolicy{
+			MaxAttempts:    2,
+			RetryableError: func(err error) bool { return true },
+		}),
+	)
+	defer teardown()
+
+	r.HandleFunc("/rates", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, fixture("rates.json"))
+	})
+
+	if _, _, err := client.Rates(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "retrying") {
+		t.Errorf("Expected the retry to be logged but got: %q", buf.String())
+	}
+	if strings.Contains(buf.String(), "secret-key") {
+		t.Errorf("Expected API key to be redacted from logs but got: %q", buf.String())
+	}
+}
+
+func TestOptionsRetryPolicyCopied(t *testing.T) {
+	policy := &RetryPolicy{MaxAttempts: 3, RetryableStatusCodes: []int{http.StatusBadGateway}}
+	c := New(WithRetryPolicy(policy))
+
+	policy.MaxAttempts = 10
+	policy.RetryableStatusCodes[0] = http.StatusTeapot
+	if c.retryPolicy.MaxAttempts != 3 || c.retryPolicy.RetryableStatusCodes[0] != http.StatusBadGateway {
+		t.Errorf("Expected client to keep its own copy of the retry policy, got %+v", c.retryPolicy)
+	}
+}
+
+func TestClientConcurrentUse(t *testing.T) {
+	teardown := setup(WithUserAgent("coincap-test/1.0"))
+	defer teardown()
+
+	r.HandleFunc("/rates/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, fixture("ratesByID.json"))
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := client.RateByID("bitcoin"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestTransportErrorRedacted(t *testing.T) {
+	failing := func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			return nil, errors.New("proxy rejected token secret-key")
+		})
+	}
+	teardown := setup(WithAPIKey("secret-key"), WithMiddleware(failing))
+	defer teardown()
+
+	_, _, err := client.Rates()
+	if err == nil || strings.Contains(err.Error(), "secret-key") {
+		t.Errorf("Expected a transport error with the API key redacted but got: %v", err)
+	}
+}
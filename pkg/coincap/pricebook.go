@@ -0,0 +1,241 @@
+package coincap
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// PriceSource tells where the price held by a PriceBook came from
+type PriceSource int
+
+const (
+	// SourceSnapshot is a price fetched from the assets endpoint
+	SourceSnapshot PriceSource = iota
+	// SourceStream is a price pushed by the prices feed
+	SourceStream
+)
+
+func (s PriceSource) String() string {
+	switch s {
+	case SourceSnapshot:
+		return "snapshot"
+	case SourceStream:
+		return "stream"
+	}
+	return fmt.Sprintf("PriceSource(%d)", int(s))
+}
+
+// BookPrice is the latest known price of an asset
+type BookPrice struct {
+	AssetID   string      // unique identifier for the asset
+	PriceUSD  string      // volume-weighted price in USD
+	UpdatedAt time.Time   // time the book learned of the price
+	Source    PriceSource // whether the price came from a snapshot or the stream
+	Stale     bool        // whether the price is older than PriceBookOptions.MaxAge
+}
+
+// PriceUSDDecimal parses PriceUSD as an exact Decimal
+func (p BookPrice) PriceUSDDecimal() (Decimal, error) {
+	return parseDecimalField("priceUsd", p.PriceUSD)
+}
+
+// PriceBookOptions configures a PriceBook
+type PriceBookOptions struct {
+	// MaxAge is how old a price may get before it is reported as stale. 0 never marks prices stale
+	MaxAge time.Duration
+	// Stream configures the book's connection to the prices feed. Unless a Reconnect policy
+	// is set DefaultReconnectPolicy is used, since a book without one stops updating on the
+	// first dropped connection
+	Stream *StreamOptions
+}
+
+// PriceBook keeps the latest prices of a set of assets in memory. It is seeded from the
+// assets endpoint, kept current by the prices feed and seeded again after every reconnection
+// so prices missed while disconnected are caught up on. A PriceBook is safe for concurrent use
+type PriceBook struct {
+	client   *Client
+	assetIDs []string
+	maxAge   time.Duration
+	stream   *PriceStream
+	cancel   context.CancelFunc
+	done     chan struct{}
+
+	mu     sync.RWMutex
+	prices map[string]BookPrice
+}
+
+// PriceBook subscribes to the prices of the given assets, or of every asset if AllAssets
+// is passed, and returns once the book has been seeded. The book is updated until ctx is done,
+// Close is called or the connection fails for good
+func (c *Client) PriceBook(ctx context.Context, opts *PriceBookOptions, assetIDs ...string) (*PriceBook, error) {
+	if len(assetIDs) == 0 {
+		return nil, fmt.Errorf("At least one asset ID or AllAssets is required")
+	}
+
+	var streamOpts StreamOptions
+	b := &PriceBook{
+		client:   c,
+		assetIDs: assetIDs,
+		done:     make(chan struct{}),
+		prices:   make(map[string]BookPrice),
+	}
+	if opts != nil {
+		b.maxAge = opts.MaxAge
+		if opts.Stream != nil {
+			streamOpts = *opts.Stream
+		}
+	}
+	if streamOpts.Reconnect == nil {
+		streamOpts.Reconnect = DefaultReconnectPolicy()
+	}
+
+	// subscribe before taking the snapshot so no update between the two is missed
+	ctx, b.cancel = context.WithCancel(ctx)
+	stream, err := c.PriceStreamWithOptions(ctx, &streamOpts, assetIDs...)
+	if err != nil {
+		b.cancel()
+		return nil, err
+	}
+	if err := b.sync(ctx); err != nil {
+		b.cancel()
+		stream.Close()
+		return nil, err
+	}
+	b.stream = stream
+
+	go b.run(ctx)
+	return b, nil
+}
+
+// sync seeds the book from the assets endpoint. Prices the stream delivered
+// after the snapshot was requested are newer and kept
+func (b *PriceBook) sync(ctx context.Context) error {
+	requested := b.client.clock.Now()
+
+	var assets []*Asset
+	if len(b.assetIDs) == 1 && b.assetIDs[0] == AllAssets {
+		it := b.client.AssetsIterator(ctx, nil, nil)
+		for it.Next() {
+			assets = append(assets, it.Value())
+		}
+		if err := it.Err(); err != nil {
+			return err
+		}
+	} else {
+		var err error
+		if assets, _, _, err = b.client.AssetsByIDsWithContext(ctx, b.assetIDs); err != nil {
+			return err
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, asset := range assets {
+		if asset.PriceUsd == "" {
+			continue
+		}
+		b.set(BookPrice{AssetID: asset.ID, PriceUSD: asset.PriceUsd, UpdatedAt: requested, Source: SourceSnapshot})
+	}
+	return nil
+}
+
+// run applies the updates of the stream and resyncs after reconnections until the stream stops
+func (b *PriceBook) run(ctx context.Context) {
+	defer close(b.done)
+	defer b.cancel()
+	updates, gaps := b.stream.Updates(), b.stream.Gaps()
+	for updates != nil || gaps != nil {
+		select {
+		case u, ok := <-updates:
+			if !ok {
+				updates = nil
+				continue
+			}
+			b.mu.Lock()
+			b.set(BookPrice{AssetID: u.AssetID, PriceUSD: u.PriceUSD, UpdatedAt: u.ReceivedAt, Source: SourceStream})
+			b.mu.Unlock()
+		case gap, ok := <-gaps:
+			if !ok {
+				gaps = nil
+				continue
+			}
+			// prices may have moved while disconnected. A failed resync leaves the old
+			// prices to turn stale until the next update or reconnection
+			if err := b.sync(ctx); err != nil && ctx.Err() == nil {
+				b.client.logf("coincap: Resyncing prices after a %v gap failed: %v", gap.Duration(), err)
+			}
+		}
+	}
+}
+
+// set stores p unless the book already holds a newer price. b.mu must be held
+func (b *PriceBook) set(p BookPrice) {
+	if old, ok := b.prices[p.AssetID]; ok && old.UpdatedAt.After(p.UpdatedAt) {
+		return
+	}
+	b.prices[p.AssetID] = p
+}
+
+// withStale marks p as stale if it is older than the book's MaxAge
+func (b *PriceBook) withStale(p BookPrice, now time.Time) BookPrice {
+	p.Stale = b.maxAge > 0 && now.Sub(p.UpdatedAt) > b.maxAge
+	return p
+}
+
+// Get returns the latest price of an asset and whether the book has one
+func (b *PriceBook) Get(assetID string) (BookPrice, bool) {
+	b.mu.RLock()
+	p, ok := b.prices[assetID]
+	b.mu.RUnlock()
+	if !ok {
+		return BookPrice{}, false
+	}
+	return b.withStale(p, b.client.clock.Now()), true
+}
+
+// Prices returns the latest price of every asset in the book, in order of asset id
+func (b *PriceBook) Prices() []BookPrice {
+	now := b.client.clock.Now()
+	b.mu.RLock()
+	prices := make([]BookPrice, 0, len(b.prices))
+	for _, p := range b.prices {
+		prices = append(prices, b.withStale(p, now))
+	}
+	b.mu.RUnlock()
+
+	sort.Slice(prices, func(i, j int) bool { return prices[i].AssetID < prices[j].AssetID })
+	return prices
+}
+
+// Stale returns the ids of the assets whose prices are older than MaxAge, in order
+func (b *PriceBook) Stale() []string {
+	var ids []string
+	for _, p := range b.Prices() {
+		if p.Stale {
+			ids = append(ids, p.AssetID)
+		}
+	}
+	return ids
+}
+
+// Err returns the error that stopped the book's connection, or nil if it is still running or
+// was stopped by Close or its context. The book keeps serving its last prices after it stopped
+func (b *PriceBook) Err() error {
+	return b.stream.Err()
+}
+
+// Done returns a channel that is closed once the book stops updating
+func (b *PriceBook) Done() <-chan struct{} {
+	return b.done
+}
+
+// Close stops updating the book and closes its connection
+func (b *PriceBook) Close() error {
+	b.cancel()
+	b.stream.Close()
+	<-b.done
+	return nil
+}
@@ -0,0 +1,155 @@
+package coincap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// serveSnapshots serves the assets endpoint with bitcoin and ethereum prices that
+// are multiplied by the number of the request
+func serveSnapshots() *connCounter {
+	var calls connCounter
+	r.HandleFunc("/assets", func(w http.ResponseWriter, req *http.Request) {
+		n := calls.next()
+		fmt.Fprintf(w, `{"data":[{"id":"bitcoin","priceUsd":"%d"},{"id":"ethereum","priceUsd":"%d"}],"timestamp":1536336916000}`, 100*n, 10*n)
+	})
+	return &calls
+}
+
+func TestPriceBook(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+	clk := newFakeClock()
+	client.clock = clk
+	serveSnapshots()
+
+	var conns connCounter
+	send, drop := make(chan string), make(chan struct{})
+	serveWebsocket("/prices", func(conn *websocket.Conn, req *http.Request) {
+		if conns.next() == 1 {
+			conn.WriteMessage(websocket.TextMessage, []byte(<-send))
+			<-drop
+			conn.UnderlyingConn().Close()
+			return
+		}
+		drain(conn)
+	})
+
+	book, err := client.PriceBook(context.Background(), &PriceBookOptions{MaxAge: time.Minute}, "bitcoin", "ethereum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer book.Close()
+
+	seeded := clk.Now()
+	expected := []BookPrice{
+		{AssetID: "bitcoin", PriceUSD: "100", UpdatedAt: seeded, Source: SourceSnapshot},
+		{AssetID: "ethereum", PriceUSD: "10", UpdatedAt: seeded, Source: SourceSnapshot},
+	}
+	if prices := book.Prices(); !reflect.DeepEqual(prices, expected) {
+		t.Errorf("Expected the book to be seeded with %+v but got %+v", expected, prices)
+	}
+
+	// prices turn stale unless updated
+	clk.After(2 * time.Minute)
+	send <- `{"bitcoin":"101"}`
+	waitFor(t, "the streamed price", func() bool {
+		p, _ := book.Get("bitcoin")
+		return p.Source == SourceStream
+	})
+	if p, _ := book.Get("bitcoin"); p.PriceUSD != "101" || p.Stale {
+		t.Errorf("Expected a fresh streamed bitcoin price but got %+v", p)
+	}
+	if stale := book.Stale(); !reflect.DeepEqual(stale, []string{"ethereum"}) {
+		t.Errorf("Expected ethereum to be stale but got %v", stale)
+	}
+
+	// a reconnection catches up on prices missed while disconnected
+	close(drop)
+	waitFor(t, "the resync", func() bool {
+		p, _ := book.Get("ethereum")
+		return p.PriceUSD == "20"
+	})
+	if p, _ := book.Get("bitcoin"); p.PriceUSD != "200" || p.Source != SourceSnapshot {
+		t.Errorf("Expected bitcoin to be resynced but got %+v", p)
+	}
+	if stale := book.Stale(); len(stale) != 0 {
+		t.Errorf("Expected no stale prices after the resync but got %v", stale)
+	}
+	if _, ok := book.Get("dogecoin"); ok {
+		t.Errorf("Expected no price for an asset the book does not follow")
+	}
+}
+
+func TestPriceBookKeepsNewerStreamPrices(t *testing.T) {
+	book := &PriceBook{prices: make(map[string]BookPrice)}
+	now := time.Now()
+	book.set(BookPrice{AssetID: "bitcoin", PriceUSD: "101", UpdatedAt: now, Source: SourceStream})
+	book.set(BookPrice{AssetID: "bitcoin", PriceUSD: "100", UpdatedAt: now.Add(-time.Second), Source: SourceSnapshot})
+	if p := book.prices["bitcoin"]; p.PriceUSD != "101" {
+		t.Errorf("Expected an older snapshot not to replace a streamed price but got %+v", p)
+	}
+}
+
+func TestPriceBookAllAssets(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+	serveSnapshots()
+	serveWebsocket("/prices", func(conn *websocket.Conn, req *http.Request) {
+		if assets := req.URL.Query().Get("assets"); assets != "ALL" {
+			t.Errorf("Expected to subscribe to ALL but got %q", assets)
+		}
+		drain(conn)
+	})
+
+	book, err := client.PriceBook(context.Background(), nil, AllAssets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, ok := book.Get("ethereum"); !ok || p.PriceUSD != "10" || p.Stale {
+		t.Errorf("Expected ethereum from the snapshot but got %+v", p)
+	}
+
+	book.Close()
+	select {
+	case <-book.Done():
+	default:
+		t.Errorf("Expected the book to stop after Close")
+	}
+	if book.Err() != nil {
+		t.Errorf("Expected a clean shutdown but got %v", book.Err())
+	}
+	if _, ok := book.Get("bitcoin"); !ok {
+		t.Errorf("Expected the book to keep its prices after Close")
+	}
+}
+
+func TestPriceBookSeedFails(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	closed := make(chan struct{})
+	serveWebsocket("/prices", func(conn *websocket.Conn, req *http.Request) {
+		drain(conn)
+		close(closed)
+	})
+	r.HandleFunc("/assets", func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	if _, err := client.PriceBook(context.Background(), nil, "bitcoin"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected the failed snapshot to be returned but got %v", err)
+	}
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Errorf("Expected the connection to be closed")
+	}
+}
@@ -0,0 +1,356 @@
+package coincap
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// PriceHub shares one connection to the prices feed between many subscribers, each
+// interested in their own set of assets. The connection subscribes to the union of the
+// subscribers' assets, is widened or narrowed as subscriptions come and go and is closed
+// while there are no subscribers. A PriceHub is safe for concurrent use
+type PriceHub struct {
+	client *Client
+	opts   StreamOptions
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	subs     map[*PriceSubscription]bool
+	refs     map[string]int // number of subscribers for each asset id, including AllAssets
+	ids      atomic.Value   // []string the connection subscribes to, read without the lock when dialing
+	upstream *PriceStream
+	closed   bool
+	err      error
+}
+
+// PriceSubscription receives the prices of the assets it subscribed to from a PriceHub
+type PriceSubscription struct {
+	dropped uint64 // atomic, first for alignment like stream.dropped
+
+	hub     *PriceHub
+	all     bool
+	assets  map[string]bool
+	updates chan PriceUpdate
+	gaps    chan StreamGap
+	quit    chan struct{}
+	once    sync.Once
+
+	mu     sync.Mutex // held while delivering so updates and gaps are not closed mid send
+	closed bool
+}
+
+// PriceHub returns a hub multiplexing the prices feed. opts.BufferSize and opts.Backpressure
+// apply to every subscription, deciding what happens when a subscriber falls behind.
+// Note that with BackpressureBlock one slow subscriber holds up all the others.
+// opts.Reconnect applies to the shared connection. The hub stops when ctx is done or Close is called
+func (c *Client) PriceHub(ctx context.Context, opts *StreamOptions) *PriceHub {
+	ctx, cancel := context.WithCancel(ctx)
+	h := &PriceHub{
+		client: c,
+		ctx:    ctx,
+		cancel: cancel,
+		subs:   make(map[*PriceSubscription]bool),
+		refs:   make(map[string]int),
+	}
+	if opts != nil {
+		h.opts = *opts
+	}
+	h.ids.Store([]string(nil))
+	go func() {
+		<-ctx.Done()
+		h.Close()
+	}()
+	return h
+}
+
+// Subscribe returns a subscription to the prices of the given assets, or of every asset if AllAssets
+// is passed. The shared connection is opened or widened as needed before Subscribe returns
+func (h *PriceHub) Subscribe(assetIDs ...string) (*PriceSubscription, error) {
+	if len(assetIDs) == 0 {
+		return nil, fmt.Errorf("At least one asset ID or AllAssets is required")
+	}
+	sub := &PriceSubscription{
+		hub:     h,
+		assets:  make(map[string]bool),
+		updates: make(chan PriceUpdate, h.opts.bufferSize()),
+		gaps:    make(chan StreamGap, gapBuffer),
+		quit:    make(chan struct{}),
+	}
+	for _, id := range assetIDs {
+		if id == AllAssets {
+			sub.all = true
+		}
+		sub.assets[id] = true
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, fmt.Errorf("PriceHub is closed")
+	}
+
+	for id := range sub.assets {
+		h.refs[id]++
+	}
+	h.subs[sub] = true
+	changed := h.updateIDs()
+
+	if h.upstream == nil {
+		if err := h.connect(); err != nil {
+			h.remove(sub)
+			h.updateIDs()
+			return nil, err
+		}
+	} else if changed {
+		h.upstream.resubscribe()
+	}
+	return sub, nil
+}
+
+// Unsubscribe stops sub, narrowing or closing the shared connection if nobody else needs its assets.
+// It is the same as calling sub.Close
+func (h *PriceHub) Unsubscribe(sub *PriceSubscription) {
+	sub.Close()
+}
+
+// unsubscribe removes sub from the hub
+func (h *PriceHub) unsubscribe(sub *PriceSubscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.subs[sub] {
+		return
+	}
+	h.remove(sub)
+	changed := h.updateIDs()
+
+	switch {
+	case h.upstream == nil:
+	case len(h.subs) == 0:
+		// closing waits for the fan out, which must not wait for the lock
+		upstream := h.upstream
+		h.upstream = nil
+		go upstream.Close()
+	case changed:
+		h.upstream.resubscribe()
+	}
+}
+
+// remove drops sub and its asset references. h.mu must be held
+func (h *PriceHub) remove(sub *PriceSubscription) {
+	delete(h.subs, sub)
+	for id := range sub.assets {
+		if h.refs[id]--; h.refs[id] == 0 {
+			delete(h.refs, id)
+		}
+	}
+}
+
+// updateIDs recomputes the assets the shared connection subscribes to from the subscribers'
+// and reports whether they changed. h.mu must be held
+func (h *PriceHub) updateIDs() bool {
+	var ids []string
+	if h.refs[AllAssets] > 0 {
+		ids = []string{AllAssets}
+	} else {
+		for id := range h.refs {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+	}
+	old := h.assetIDs()
+	h.ids.Store(ids)
+	return strings.Join(old, ",") != strings.Join(ids, ",")
+}
+
+// assetIDs returns the assets the shared connection subscribes to
+func (h *PriceHub) assetIDs() []string {
+	return h.ids.Load().([]string)
+}
+
+// connect opens the shared connection and starts fanning out its updates. h.mu must be held,
+// so concurrent subscribers wait for the dial rather than opening connections of their own
+func (h *PriceHub) connect() error {
+	upstream, err := h.client.priceStream(h.ctx, &StreamOptions{Reconnect: h.opts.Reconnect}, h.assetIDs)
+	if err != nil {
+		return err
+	}
+	h.upstream = upstream
+	go h.fanOut(upstream)
+	return nil
+}
+
+// fanOut delivers the updates and gaps of upstream to the interested subscribers
+func (h *PriceHub) fanOut(upstream *PriceStream) {
+	gaps := upstream.Gaps()
+	updates := upstream.Updates()
+	for updates != nil || gaps != nil {
+		select {
+		case u, ok := <-updates:
+			if !ok {
+				updates = nil
+				continue
+			}
+			for _, sub := range h.subscribers(upstream) {
+				if sub.all || sub.assets[u.AssetID] {
+					sub.deliver(h.ctx, h.opts.backpressure(), u)
+				}
+			}
+		case gap, ok := <-gaps:
+			if !ok {
+				gaps = nil
+				continue
+			}
+			for _, sub := range h.subscribers(upstream) {
+				sub.addGap(gap)
+			}
+		}
+	}
+
+	// the connection failed for good, so there is nothing left to deliver
+	if err := upstream.Err(); err != nil {
+		h.mu.Lock()
+		current := h.upstream == upstream
+		if current {
+			h.err = err
+		}
+		h.mu.Unlock()
+		if current {
+			h.Close()
+		}
+	}
+}
+
+// subscribers returns a snapshot of the current subscriptions, or none if upstream is no longer
+// the hub's connection, so a connection being closed cannot reach subscribers of its successor
+func (h *PriceHub) subscribers(upstream *PriceStream) []*PriceSubscription {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.upstream != upstream {
+		return nil
+	}
+	subs := make([]*PriceSubscription, 0, len(h.subs))
+	for sub := range h.subs {
+		subs = append(subs, sub)
+	}
+	return subs
+}
+
+// Err returns the error that stopped the hub's connection for good, if any
+func (h *PriceHub) Err() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.err
+}
+
+// Close closes every subscription and the shared connection
+func (h *PriceHub) Close() error {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return nil
+	}
+	h.closed = true
+	upstream := h.upstream
+	h.upstream = nil
+	subs := make([]*PriceSubscription, 0, len(h.subs))
+	for sub := range h.subs {
+		subs = append(subs, sub)
+	}
+	h.mu.Unlock()
+
+	h.cancel()
+	for _, sub := range subs {
+		sub.Close()
+	}
+	if upstream != nil {
+		upstream.Close()
+	}
+	return nil
+}
+
+// deliver hands u to the subscriber according to policy
+func (s *PriceSubscription) deliver(ctx context.Context, policy Backpressure, u PriceUpdate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	applyBackpressure(policy, s.drop, func(wait bool) bool {
+		if !wait {
+			select {
+			case s.updates <- u:
+				return true
+			default:
+				return false
+			}
+		}
+		select {
+		case s.updates <- u:
+			return true
+		case <-s.quit:
+		case <-ctx.Done():
+		}
+		return false
+	}, func() bool {
+		select {
+		case <-s.updates:
+			return true
+		default:
+			return false
+		}
+	})
+}
+
+// addGap reports a reconnection of the shared connection unless the subscription is closed
+func (s *PriceSubscription) addGap(gap StreamGap) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	offerGap(s.gaps, gap)
+}
+
+// drop counts an update discarded because the subscriber fell behind
+func (s *PriceSubscription) drop() {
+	atomic.AddUint64(&s.dropped, 1)
+}
+
+// Updates returns the channel the subscribed prices are delivered on.
+// It is closed when the subscription or the hub is closed
+func (s *PriceSubscription) Updates() <-chan PriceUpdate {
+	return s.updates
+}
+
+// Gaps returns the channel reconnections of the shared connection are reported on, including
+// those made when the hub's subscriptions change.
+// It is closed when the subscription or the hub is closed
+func (s *PriceSubscription) Gaps() <-chan StreamGap {
+	return s.gaps
+}
+
+// Dropped returns the number of updates discarded because the subscriber fell behind
+func (s *PriceSubscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Close ends the subscription and closes its channels
+func (s *PriceSubscription) Close() error {
+	s.once.Do(func() {
+		// unblock a pending delivery before waiting for it
+		close(s.quit)
+		s.hub.unsubscribe(s)
+
+		s.mu.Lock()
+		s.closed = true
+		close(s.updates)
+		close(s.gaps)
+		s.mu.Unlock()
+	})
+	return nil
+}
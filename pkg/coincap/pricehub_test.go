@@ -0,0 +1,292 @@
+package coincap
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// receiveQuery returns the assets query of the next connection or fails the test after a second
+func receiveQuery(t *testing.T, queries <-chan string) string {
+	t.Helper()
+	select {
+	case q := <-queries:
+		return q
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for a connection")
+	}
+	return ""
+}
+
+func TestPriceHub(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	var conns connCounter
+	queries := make(chan string, 10)
+	closed := make(chan int, 10)
+	serveWebsocket("/prices", func(conn *websocket.Conn, req *http.Request) {
+		n := conns.next()
+		assets := req.URL.Query().Get("assets")
+		queries <- assets
+		// every connection publishes its number as the price of each subscribed asset
+		prices := make([]string, 0)
+		for _, id := range strings.Split(assets, ",") {
+			prices = append(prices, fmt.Sprintf("%q:\"%d\"", id, n))
+		}
+		conn.WriteMessage(websocket.TextMessage, []byte("{"+strings.Join(prices, ",")+"}"))
+		drain(conn)
+		closed <- n
+	})
+
+	hub := client.PriceHub(context.Background(), nil)
+	defer hub.Close()
+
+	btc, err := hub.Subscribe("bitcoin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q := receiveQuery(t, queries); q != "bitcoin" {
+		t.Errorf("Expected the first connection to subscribe to bitcoin but got %q", q)
+	}
+	if u := receive(t, btc.Updates()); u.AssetID != "bitcoin" || u.PriceUSD != "1" {
+		t.Errorf("Expected bitcoin from the first connection but got %+v", u)
+	}
+
+	both, err := hub.Subscribe("ethereum", "bitcoin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q := receiveQuery(t, queries); q != "bitcoin,ethereum" {
+		t.Errorf("Expected the connection to widen to bitcoin,ethereum but got %q", q)
+	}
+	if n := <-closed; n != 1 {
+		t.Errorf("Expected the first connection to be replaced but connection %d closed", n)
+	}
+	if u := receive(t, btc.Updates()); u.AssetID != "bitcoin" || u.PriceUSD != "2" {
+		t.Errorf("Expected only bitcoin for the first subscriber but got %+v", u)
+	}
+	if gap := receiveGap(t, btc.Gaps()); gap.Err != errResubscribe || gap.Attempts != 1 {
+		t.Errorf("Expected the switch to the widened connection to be reported but got %+v", gap)
+	}
+	for _, id := range []string{"bitcoin", "ethereum"} {
+		if u := receive(t, both.Updates()); u.AssetID != id || u.PriceUSD != "2" {
+			t.Errorf("Expected %s from the second connection but got %+v", id, u)
+		}
+	}
+
+	hub.Unsubscribe(both)
+	waitClosed(t, both.Updates())
+	if q := receiveQuery(t, queries); q != "bitcoin" {
+		t.Errorf("Expected the connection to narrow to bitcoin but got %q", q)
+	}
+	if u := receive(t, btc.Updates()); u.PriceUSD != "3" {
+		t.Errorf("Expected bitcoin from the third connection but got %+v", u)
+	}
+
+	btc.Close()
+	waitClosed(t, btc.Updates())
+	for _, want := range []int{2, 3} {
+		select {
+		case n := <-closed:
+			if n != want {
+				t.Errorf("Expected connection %d to close but connection %d did", want, n)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Expected the connection to close with the last subscriber")
+		}
+	}
+	if n := conns.next() - 1; n != 3 {
+		t.Errorf("Expected 3 connections but got %d", n)
+	}
+}
+
+func TestPriceHubSlowSubscriber(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	send := make(chan string)
+	serveWebsocket("/prices", func(conn *websocket.Conn, req *http.Request) {
+		for price := range send {
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"bitcoin":"`+price+`"}`))
+		}
+	})
+	defer close(send)
+
+	hub := client.PriceHub(context.Background(), &StreamOptions{BufferSize: 1, Backpressure: BackpressureDropNewest})
+	defer hub.Close()
+	slow, err := hub.Subscribe("bitcoin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fast, err := hub.Subscribe("bitcoin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, price := range []string{"1", "2", "3"} {
+		send <- price
+		if u := receive(t, fast.Updates()); u.PriceUSD != price {
+			t.Errorf("Expected the fast subscriber to get %s but got %s", price, u.PriceUSD)
+		}
+	}
+	waitFor(t, "updates to be dropped", func() bool { return slow.Dropped() == 2 })
+	if u := receive(t, slow.Updates()); u.PriceUSD != "1" {
+		t.Errorf("Expected the slow subscriber to keep the first price but got %s", u.PriceUSD)
+	}
+	if fast.Dropped() != 0 {
+		t.Errorf("Expected nothing dropped for the fast subscriber but got %d", fast.Dropped())
+	}
+}
+
+func TestPriceHubConcurrent(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	serveWebsocket("/prices", func(conn *websocket.Conn, req *http.Request) {
+		go drain(conn)
+		for {
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"bitcoin":"1"}`)); err != nil {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	})
+
+	hub := client.PriceHub(context.Background(), &StreamOptions{Backpressure: BackpressureDropOldest})
+	defer hub.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				sub, err := hub.Subscribe("bitcoin", fmt.Sprintf("asset-%d", i))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				select {
+				case <-sub.Updates():
+				case <-time.After(5 * time.Millisecond):
+				}
+				sub.Close()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if ids := hub.assetIDs(); len(ids) != 0 {
+		t.Errorf("Expected no assets left after every subscriber left but got %v", ids)
+	}
+	if hub.Err() != nil {
+		t.Errorf("Expected the hub to stay healthy but got %v", hub.Err())
+	}
+}
+
+func TestPriceHubReplacedConnection(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	// every connection keeps publishing its number as the price
+	var conns connCounter
+	serveWebsocket("/prices", func(conn *websocket.Conn, req *http.Request) {
+		n := conns.next()
+		go drain(conn)
+		for {
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"bitcoin":"%d"}`, n))); err != nil {
+				return
+			}
+		}
+	})
+
+	hub := client.PriceHub(context.Background(), &StreamOptions{Backpressure: BackpressureDropOldest})
+	defer hub.Close()
+
+	for i := 1; i <= 5; i++ {
+		sub, err := hub.Subscribe("bitcoin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the last unsubscribe closes the connection and the next subscribe opens another
+		for j := 0; j < 50; j++ {
+			if u := receive(t, sub.Updates()); u.PriceUSD != fmt.Sprint(i) {
+				t.Fatalf("Expected prices from connection %d only but got %s", i, u.PriceUSD)
+			}
+		}
+		sub.Close()
+	}
+}
+
+func TestPriceHubCloseDuringGaps(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	// every connection drops straight away, so the hub keeps fanning out gaps
+	serveWebsocket("/prices", func(conn *websocket.Conn, req *http.Request) {
+		conn.UnderlyingConn().Close()
+	})
+
+	hub := client.PriceHub(context.Background(), &StreamOptions{Reconnect: &ReconnectPolicy{BaseBackoff: time.Microsecond}})
+	defer hub.Close()
+	keep, err := hub.Subscribe("bitcoin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	receiveGap(t, keep.Gaps())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				sub, err := hub.Subscribe("bitcoin")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				time.Sleep(time.Duration(j%3) * 100 * time.Microsecond)
+				sub.Close()
+			}
+		}()
+	}
+	wg.Wait()
+	if hub.Err() != nil {
+		t.Errorf("Expected the hub to keep reconnecting but got %v", hub.Err())
+	}
+}
+
+func TestPriceHubFails(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	serveWebsocket("/prices", func(conn *websocket.Conn, req *http.Request) {
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "maintenance"))
+		drain(conn)
+	})
+
+	hub := client.PriceHub(context.Background(), nil)
+	if _, err := hub.Subscribe(); err == nil {
+		t.Errorf("Expected subscribing to nothing to fail")
+	}
+	sub, err := hub.Subscribe(AllAssets)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	waitClosed(t, sub.Updates())
+	if !websocket.IsCloseError(hub.Err(), websocket.CloseGoingAway) {
+		t.Errorf("Expected the close to be reported but got %v", hub.Err())
+	}
+	if _, err := hub.Subscribe("bitcoin"); err == nil {
+		t.Errorf("Expected subscribing to a stopped hub to fail")
+	}
+}
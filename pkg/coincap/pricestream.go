@@ -0,0 +1,139 @@
+package coincap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AllAssets subscribes a price stream to the prices of every asset
+const AllAssets = "ALL"
+
+// PriceUpdate is a new price of an asset pushed by the prices feed
+type PriceUpdate struct {
+	AssetID    string    // unique identifier for the asset
+	PriceUSD   string    // volume-weighted price in USD
+	ReceivedAt time.Time // time the update was received
+}
+
+// PriceUSDDecimal parses PriceUSD as an exact Decimal
+func (u PriceUpdate) PriceUSDDecimal() (Decimal, error) {
+	return parseDecimalField("priceUsd", u.PriceUSD)
+}
+
+// PriceStream delivers realtime prices from the wss://ws.coincap.io/prices feed.
+// Use it like
+//
+//	stream, err := client.PriceStream(ctx, "bitcoin", "ethereum")
+//	...
+//	for update := range stream.Updates() {
+//		fmt.Println(update.AssetID, update.PriceUSD)
+//	}
+//	if err := stream.Err(); err != nil {
+//		...
+//	}
+type PriceStream struct {
+	*stream
+	updates chan PriceUpdate
+}
+
+// PriceStream connects to the prices feed and streams the prices of the given assets,
+// or of every asset if AllAssets is passed. The stream runs until ctx is done,
+// Close is called or the connection fails
+func (c *Client) PriceStream(ctx context.Context, assetIDs ...string) (*PriceStream, error) {
+	return c.PriceStreamWithOptions(ctx, nil, assetIDs...)
+}
+
+// PriceStreamWithOptions is like PriceStream but configures buffering and
+// what happens when the consumer falls behind
+func (c *Client) PriceStreamWithOptions(ctx context.Context, opts *StreamOptions, assetIDs ...string) (*PriceStream, error) {
+	if len(assetIDs) == 0 {
+		return nil, fmt.Errorf("At least one asset ID or AllAssets is required")
+	}
+
+	return c.priceStream(ctx, opts, func() []string {
+		return assetIDs
+	})
+}
+
+// priceStream connects to the prices feed for the assets returned by assetIDs,
+// which is asked again whenever the stream reconnects or resubscribes
+func (c *Client) priceStream(ctx context.Context, opts *StreamOptions, assetIDs func() []string) (*PriceStream, error) {
+	ctx, base, conn, err := c.openStream(ctx, opts, func() (string, url.Values) {
+		return "/prices", url.Values{"assets": {strings.Join(assetIDs(), ",")}}
+	})
+	if err != nil {
+		return nil, err
+	}
+	s := &PriceStream{
+		stream:  base,
+		updates: make(chan PriceUpdate, opts.bufferSize()),
+	}
+	go func() {
+		defer close(s.done)
+		defer close(s.updates)
+		s.run(ctx, conn, func(msg []byte, received time.Time) error {
+			return s.handle(ctx, msg, received)
+		})
+	}()
+	return s, nil
+}
+
+// handle decodes a message of the prices feed and delivers its updates
+func (s *PriceStream) handle(ctx context.Context, msg []byte, received time.Time) error {
+	// messages map asset ids to prices, e.g. {"bitcoin":"6929.82"}
+	var prices map[string]string
+	if err := json.Unmarshal(msg, &prices); err != nil {
+		return newDecodeError("/prices", err)
+	}
+	ids := make([]string, 0, len(prices))
+	for id := range prices {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		if !s.deliver(ctx, PriceUpdate{AssetID: id, PriceUSD: prices[id], ReceivedAt: received}) {
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// deliver hands u to the consumer according to the stream's backpressure policy.
+// It returns false if ctx ended first
+func (s *PriceStream) deliver(ctx context.Context, u PriceUpdate) bool {
+	return s.offer(func(wait bool) bool {
+		if !wait {
+			select {
+			case s.updates <- u:
+				return true
+			default:
+				return false
+			}
+		}
+		select {
+		case s.updates <- u:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}, func() bool {
+		select {
+		case <-s.updates:
+			return true
+		default:
+			return false
+		}
+	})
+}
+
+// Updates returns the channel prices are delivered on. Updates in the same message are
+// delivered in order of asset id. The channel is closed when the stream stops
+func (s *PriceStream) Updates() <-chan PriceUpdate {
+	return s.updates
+}
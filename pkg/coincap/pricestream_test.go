@@ -0,0 +1,224 @@
+package coincap
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+var upgrader = websocket.Upgrader{}
+
+// serveWebsocket upgrades requests to path and hands the connection to handler,
+// closing it once handler returns
+func serveWebsocket(path string, handler func(conn *websocket.Conn, req *http.Request)) {
+	r.HandleFunc(path, func(w http.ResponseWriter, req *http.Request) {
+		conn, err := upgrader.Upgrade(w, req, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		handler(conn, req)
+	})
+}
+
+// drain reads from conn until the client goes away
+func drain(conn *websocket.Conn) {
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+// receive returns the next value from updates or fails the test after a second
+func receive(t *testing.T, updates <-chan PriceUpdate) PriceUpdate {
+	t.Helper()
+	select {
+	case u, ok := <-updates:
+		if !ok {
+			t.Fatal("Expected an update but the stream stopped")
+		}
+		return u
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for an update")
+	}
+	return PriceUpdate{}
+}
+
+// waitClosed fails the test if updates is not closed within a second
+func waitClosed(t *testing.T, updates <-chan PriceUpdate) {
+	t.Helper()
+	for {
+		select {
+		case _, ok := <-updates:
+			if !ok {
+				return
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for the stream to stop")
+		}
+	}
+}
+
+func TestPriceStream(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	serveWebsocket("/prices", func(conn *websocket.Conn, req *http.Request) {
+		if assets := req.URL.Query().Get("assets"); assets != "bitcoin,ethereum" {
+			t.Errorf("Expected to subscribe to bitcoin,ethereum but got %q", assets)
+		}
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"ethereum":"227.1093","bitcoin":"6929.8217"}`))
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"bitcoin":"6930.0000"}`))
+		drain(conn)
+	})
+
+	stream, err := client.PriceStream(context.Background(), "bitcoin", "ethereum")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []PriceUpdate{
+		{AssetID: "bitcoin", PriceUSD: "6929.8217"},
+		{AssetID: "ethereum", PriceUSD: "227.1093"},
+		{AssetID: "bitcoin", PriceUSD: "6930.0000"},
+	}
+	for _, e := range expected {
+		u := receive(t, stream.Updates())
+		if u.AssetID != e.AssetID || u.PriceUSD != e.PriceUSD {
+			t.Errorf("Expected %s at %s but got %s at %s", e.AssetID, e.PriceUSD, u.AssetID, u.PriceUSD)
+		}
+		if u.ReceivedAt.IsZero() {
+			t.Errorf("Expected the receive time to be set")
+		}
+	}
+
+	if err := stream.Close(); err != nil {
+		t.Fatal(err)
+	}
+	waitClosed(t, stream.Updates())
+	if err := stream.Err(); err != nil {
+		t.Errorf("Expected a clean shutdown but got %v", err)
+	}
+}
+
+func TestPriceStreamAllAssets(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	serveWebsocket("/prices", func(conn *websocket.Conn, req *http.Request) {
+		if assets := req.URL.Query().Get("assets"); assets != "ALL" {
+			t.Errorf("Expected to subscribe to ALL but got %q", assets)
+		}
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"dogecoin":"0.0057"}`))
+		drain(conn)
+	})
+
+	stream, err := client.PriceStream(context.Background(), AllAssets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	u := receive(t, stream.Updates())
+	price, err := u.PriceUSDDecimal()
+	if err != nil || price.String() != "0.0057" {
+		t.Errorf("Expected dogecoin at 0.0057 but got %s (%v)", price, err)
+	}
+}
+
+func TestPriceStreamContextCanceled(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	serverDone := make(chan struct{})
+	serveWebsocket("/prices", func(conn *websocket.Conn, req *http.Request) {
+		drain(conn)
+		close(serverDone)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := client.PriceStream(ctx, "bitcoin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+
+	waitClosed(t, stream.Updates())
+	if err := stream.Err(); err != nil {
+		t.Errorf("Expected a clean shutdown but got %v", err)
+	}
+	select {
+	case <-serverDone:
+	case <-time.After(time.Second):
+		t.Errorf("Expected the connection to be closed")
+	}
+}
+
+func TestPriceStreamServerClosed(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	serveWebsocket("/prices", func(conn *websocket.Conn, req *http.Request) {
+		msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "restarting")
+		conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+	})
+
+	stream, err := client.PriceStream(context.Background(), "bitcoin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitClosed(t, stream.Updates())
+	if !websocket.IsCloseError(stream.Err(), websocket.CloseGoingAway) {
+		t.Errorf("Expected the close to be reported but got %v", stream.Err())
+	}
+}
+
+func TestPriceStreamMalformed(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	serveWebsocket("/prices", func(conn *websocket.Conn, req *http.Request) {
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"bitcoin":6929.82}`))
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"bitcoin":"6929.82"}`))
+		drain(conn)
+	})
+
+	stream, err := client.PriceStream(context.Background(), "bitcoin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	if u := receive(t, stream.Updates()); u.PriceUSD != "6929.82" {
+		t.Errorf("Expected the malformed message to be skipped but got %+v", u)
+	}
+	if stream.Malformed() != 1 || stream.Err() != nil {
+		t.Errorf("Expected one malformed message and no error but got %d and %v", stream.Malformed(), stream.Err())
+	}
+}
+
+func TestPriceStreamRefused(t *testing.T) {
+	teardown := setup(WithAPIKey("secret-key"))
+	defer teardown()
+
+	r.HandleFunc("/prices", func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "Bearer secret-key" {
+			t.Errorf("Expected the api key to be sent")
+		}
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"error":"invalid key"}`))
+	})
+
+	_, err := client.PriceStream(context.Background(), "bitcoin")
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected ErrUnauthorized but got %v", err)
+	}
+
+	if _, err := client.PriceStream(context.Background()); err == nil {
+		t.Errorf("Expected an error without any assets")
+	}
+}
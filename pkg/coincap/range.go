@@ -0,0 +1,268 @@
+package coincap
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// historyWindows are the longest spans /assets/{id}/history is known to serve in full
+// for each of its intervals. Longer ranges are silently truncated by the API
+var historyWindows = map[Interval]time.Duration{
+	Minute:         24 * time.Hour,
+	FifteenMinutes: 7 * 24 * time.Hour,
+	Hour:           30 * 24 * time.Hour,
+	Day:            365 * 24 * time.Hour,
+}
+
+// RangeOptions configures how a long time range is split into windows and fetched
+type RangeOptions struct {
+	Window      time.Duration // span of each request. Defaults to the largest span the API serves in one call for the interval
+	Concurrency int           // maximum number of windows fetched at once. Defaults to 1
+}
+
+// Gap is a span of a requested range for which the API returned no points.
+// Start is the time of the first missing point and End the time of the next point returned,
+// or the end of the range
+type Gap struct {
+	Start time.Time
+	End   time.Time
+}
+
+// timeWindow is one request's worth of a longer range
+type timeWindow struct {
+	start time.Time
+	end   time.Time
+}
+
+// splitRange splits start to end into consecutive windows of at most size.
+// Neighbouring windows share their boundary since the API treats both ends as inclusive
+func splitRange(start, end time.Time, size time.Duration) []timeWindow {
+	var windows []timeWindow
+	for s := start; s.Before(end); s = s.Add(size) {
+		e := s.Add(size)
+		if e.After(end) {
+			e = end
+		}
+		windows = append(windows, timeWindow{start: s, end: e})
+	}
+	return windows
+}
+
+// fetchWindows calls fetch for every window running at most concurrency calls at once.
+// The first error cancels the remaining calls and is returned.
+// A ResponseMeta requested by the caller combines the calls as described by combineMeta
+func (c *Client) fetchWindows(ctx context.Context, windows []timeWindow, concurrency int, fetch func(ctx context.Context, i int, w timeWindow) error) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+		sem      = make(chan struct{}, concurrency)
+		meta     = c.combineMeta(ctx)
+	)
+	for i, w := range windows {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int, w timeWindow) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			err := meta.request(ctx, func(ctx context.Context) error {
+				return fetch(ctx, i, w)
+			})
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(i, w)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// findGaps returns the spans of start to end not covered by times, which must be sorted.
+// A gap is reported wherever at least one point spaced step apart is missing
+func findGaps(times []time.Time, start, end time.Time, step time.Duration) []Gap {
+	var gaps []Gap
+	next := start // earliest time the next point is expected
+	for _, t := range times {
+		if t.Sub(next) >= step {
+			gaps = append(gaps, Gap{Start: next, End: t})
+		}
+		next = t.Add(step)
+	}
+	if end.Sub(next) >= step {
+		gaps = append(gaps, Gap{Start: next, End: end})
+	}
+	return gaps
+}
+
+// rangeParams validates a range request and returns the point spacing and window size to use
+func rangeParams(interval Interval, start, end time.Time, opts *RangeOptions, windows map[Interval]time.Duration) (time.Duration, time.Duration, int, error) {
+	step := interval.Duration()
+	if !start.Before(end) {
+		return 0, 0, 0, fmt.Errorf("Start %v must be before end %v", start, end)
+	}
+
+	window, ok := windows[interval]
+	if !ok {
+		window = step * maxPageSize
+	}
+	concurrency := 1
+	if opts != nil {
+		if opts.Window > 0 {
+			window = opts.Window
+		}
+		if opts.Concurrency > 0 {
+			concurrency = opts.Concurrency
+		}
+	}
+	// every window has to be able to hold at least one point
+	if window < step {
+		window = step
+	}
+	return step, window, concurrency, nil
+}
+
+// inRange reports whether t lies within start and end inclusive
+func inRange(t, start, end time.Time) bool {
+	return !t.Before(start) && !t.After(end)
+}
+
+// AssetHistoryRange returns the USD price history of an asset from start to end at the given interval,
+// however long the range. The range is split into windows the API serves in full, which are fetched
+// opts.Concurrency at a time and merged into one series ordered by time with duplicates removed.
+// A start within an interval is moved back to the beginning of that interval.
+// Any spans the API returned no points for are reported as gaps
+func (c *Client) AssetHistoryRange(ctx context.Context, id string, interval Interval, start, end time.Time, opts *RangeOptions) ([]*AssetHistory, []Gap, error) {
+	if interval == "" {
+		interval = Hour
+	}
+	if !interval.ValidForAssetHistory() {
+		return nil, nil, fmt.Errorf("Interval %q is not supported for asset history", interval)
+	}
+	step, window, concurrency, err := rangeParams(interval, start, end, opts, historyWindows)
+	if err != nil {
+		return nil, nil, err
+	}
+	// include the whole interval the range starts in, like Candles does
+	start = interval.Truncate(start)
+
+	windows := splitRange(start, end, window)
+	pages := make([][]*AssetHistory, len(windows))
+	err = c.fetchWindows(ctx, windows, concurrency, func(ctx context.Context, i int, w timeWindow) error {
+		history, _, err := c.AssetHistoryByIDWithContext(ctx, id, &AssetHistoryRequest{
+			Interval: interval,
+			Start:    &Timestamp{w.start},
+			End:      &Timestamp{w.end},
+		})
+		pages[i] = history
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// merge the windows, keeping the first point seen for each timestamp
+	seen := make(map[int64]bool)
+	var series []*AssetHistory
+	for _, page := range pages {
+		for _, h := range page {
+			ms := toMillis(h.Time.Time)
+			if seen[ms] || !inRange(h.Time.Time, start, end) {
+				continue
+			}
+			seen[ms] = true
+			series = append(series, h)
+		}
+	}
+	sort.SliceStable(series, func(i, j int) bool {
+		return series[i].Time.Before(series[j].Time.Time)
+	})
+
+	times := make([]time.Time, len(series))
+	for i, h := range series {
+		times[i] = h.Time.Time
+	}
+	return series, findGaps(times, start, end, step), nil
+}
+
+// CandlesRange returns the candles of a market from start to end however long the range.
+// ExchangeID, BaseID, QuoteID and Interval of reqParams are used, its other fields are ignored.
+// The range is split into windows of at most 2000 candles, which are fetched opts.Concurrency
+// at a time and merged into one series ordered by period with duplicates removed.
+// As with Candles, the candle containing start is included.
+// Any spans the API returned no candles for are reported as gaps
+func (c *Client) CandlesRange(ctx context.Context, reqParams *CandlesRequest, start, end time.Time, opts *RangeOptions) ([]*Candle, []Gap, error) {
+	if !reqParams.Interval.ValidForCandles() {
+		return nil, nil, fmt.Errorf("Interval %q is not supported for candles", reqParams.Interval)
+	}
+	step, window, concurrency, err := rangeParams(reqParams.Interval, start, end, opts, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	// include the whole candle the range starts in, like Candles does
+	start = reqParams.Interval.Truncate(start)
+
+	windows := splitRange(start, end, window)
+	pages := make([][]*Candle, len(windows))
+	err = c.fetchWindows(ctx, windows, concurrency, func(ctx context.Context, i int, w timeWindow) error {
+		params := CandlesRequest{
+			ExchangeID: reqParams.ExchangeID,
+			BaseID:     reqParams.BaseID,
+			QuoteID:    reqParams.QuoteID,
+			Interval:   reqParams.Interval,
+			StartTime:  &Timestamp{w.start},
+			EndTime:    &Timestamp{w.end},
+			Limit:      maxPageSize,
+		}
+		candles, _, err := c.CandlesWithContext(ctx, &params)
+		pages[i] = candles
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// merge the windows, keeping the first candle seen for each period
+	seen := make(map[int64]bool)
+	var series []*Candle
+	for _, page := range pages {
+		for _, candle := range page {
+			ms := toMillis(candle.Period.Time)
+			if seen[ms] || !inRange(candle.Period.Time, start, end) {
+				continue
+			}
+			seen[ms] = true
+			series = append(series, candle)
+		}
+	}
+	sort.SliceStable(series, func(i, j int) bool {
+		return series[i].Period.Before(series[j].Period.Time)
+	})
+
+	times := make([]time.Time, len(series))
+	for i, candle := range series {
+		times[i] = candle.Period.Time
+	}
+	return series, findGaps(times, start, end, step), nil
+}
@@ -0,0 +1,264 @@
+package coincap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// seriesHandler serves points every step between the requested start and end inclusive,
+// truncated to maxPoints like the API does. Points within the missing span are left out
+func seriesHandler(t *testing.T, step time.Duration, maxPoints int, missing Gap, point func(ms int64) string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start, err := strconv.ParseInt(r.URL.Query().Get("start"), 10, 64)
+		if err != nil {
+			t.Errorf("Expected a start parameter: %v", err)
+		}
+		end, err := strconv.ParseInt(r.URL.Query().Get("end"), 10, 64)
+		if err != nil {
+			t.Errorf("Expected an end parameter: %v", err)
+		}
+
+		var data []string
+		stepMs := int64(step / time.Millisecond)
+		for ms := start; ms <= end && len(data) < maxPoints; ms += stepMs {
+			tm := time.Unix(0, ms*1e6)
+			if !tm.Before(missing.Start) && tm.Before(missing.End) {
+				continue
+			}
+			data = append(data, point(ms))
+		}
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprintf(w, `{"data":[%s],"timestamp":1536336916333}`, strings.Join(data, ","))
+	}
+}
+
+func historyPoint(ms int64) string {
+	return fmt.Sprintf(`{"priceUsd":"1.5","time":%d}`, ms)
+}
+
+func TestAssetHistoryRange(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(3 * 24 * time.Hour)
+	missing := Gap{Start: start.Add(30 * time.Hour), End: start.Add(32 * time.Hour)}
+
+	// a single request would be truncated to the first day
+	requests := 0
+	var mu sync.Mutex
+	handler := seriesHandler(t, time.Minute, 1441, missing, historyPoint)
+	r.HandleFunc("/assets/bitcoin/history", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		if r.URL.Query().Get("interval") != "m1" {
+			t.Errorf("Expected interval m1 but got %s", r.URL.Query().Get("interval"))
+		}
+		handler(w, r)
+	})
+
+	history, gaps, err := client.AssetHistoryRange(context.Background(), "bitcoin", Minute, start, end, &RangeOptions{Concurrency: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests != 3 {
+		t.Errorf("Expected the range to be split into 3 requests but made %d", requests)
+	}
+
+	// every minute of the range inclusive, without the missing 2 hours
+	if expected := 3*24*60 + 1 - 2*60; len(history) != expected {
+		t.Errorf("Expected %d points but got %d", expected, len(history))
+	}
+	for i := 1; i < len(history); i++ {
+		if !history[i-1].Time.Before(history[i].Time.Time) {
+			t.Fatalf("Expected points to be ordered without duplicates but %v came after %v", history[i].Time, history[i-1].Time)
+		}
+	}
+
+	if len(gaps) != 1 || !gaps[0].Start.Equal(missing.Start) || !gaps[0].End.Equal(missing.End) {
+		t.Errorf("Expected gap %v but got %v", missing, gaps)
+	}
+}
+
+func TestAssetHistoryRangeConcurrency(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	var (
+		mu                sync.Mutex
+		inFlight, maxSeen int
+	)
+	handler := seriesHandler(t, time.Hour, 2000, Gap{}, historyPoint)
+	r.HandleFunc("/assets/bitcoin/history", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxSeen {
+			maxSeen = inFlight
+		}
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+		handler(w, r)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	})
+
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(10 * 24 * time.Hour)
+	history, gaps, err := client.AssetHistoryRange(context.Background(), "bitcoin", Hour, start, end, &RangeOptions{Window: 24 * time.Hour, Concurrency: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if maxSeen > 3 {
+		t.Errorf("Expected at most 3 requests at once but saw %d", maxSeen)
+	}
+	if len(history) != 10*24+1 {
+		t.Errorf("Expected %d points but got %d", 10*24+1, len(history))
+	}
+	if len(gaps) != 0 {
+		t.Errorf("Expected no gaps but got %v", gaps)
+	}
+}
+
+func TestAssetHistoryRangeMeta(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+	r.HandleFunc("/assets/bitcoin/history", seriesHandler(t, time.Hour, 2000, Gap{}, historyPoint))
+
+	var meta ResponseMeta
+	ctx := WithResponseMeta(context.Background(), &meta)
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(10 * 24 * time.Hour)
+	if _, _, err := client.AssetHistoryRange(ctx, "bitcoin", Hour, start, end, &RangeOptions{Window: 24 * time.Hour, Concurrency: 4}); err != nil {
+		t.Fatal(err)
+	}
+	if meta.Attempts != 10 || meta.StatusCode != http.StatusOK || meta.Timestamp == nil {
+		t.Errorf("Expected the windows combined into one meta but got %+v", meta)
+	}
+}
+
+func TestAssetHistoryRangeError(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	r.HandleFunc("/assets/bitcoin/history", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":"bitcoin not found"}`)
+	})
+
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	_, _, err := client.AssetHistoryRange(context.Background(), "bitcoin", Minute, start, start.Add(72*time.Hour), nil)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound but got %v", err)
+	}
+
+	if _, _, err := client.AssetHistoryRange(context.Background(), "bitcoin", Minute, start, start, nil); err == nil {
+		t.Errorf("Expected an error for an empty range")
+	}
+	if _, _, err := client.AssetHistoryRange(context.Background(), "bitcoin", "m2", start, start.Add(time.Hour), nil); err == nil {
+		t.Errorf("Expected an error for an unknown interval")
+	}
+}
+
+func TestCandlesRange(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(5000 * 5 * time.Minute)
+
+	requests := 0
+	handler := seriesHandler(t, 5*time.Minute, 2000, Gap{}, func(ms int64) string {
+		return fmt.Sprintf(`{"open":"1","high":"2","low":"0.5","close":"1.5","volume":"10","period":%d}`, ms)
+	})
+	r.HandleFunc("/candles", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Query().Get("limit") != "2000" {
+			t.Errorf("Expected the maximum limit to be requested but got %s", r.URL.Query().Get("limit"))
+		}
+		handler(w, r)
+	})
+
+	params := &CandlesRequest{ExchangeID: "poloniex", BaseID: "ethereum", QuoteID: "bitcoin", Interval: FiveMinutes}
+	candles, gaps, err := client.CandlesRange(context.Background(), params, start, end, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests != 3 {
+		t.Errorf("Expected 3 requests but made %d", requests)
+	}
+	if len(candles) != 5001 {
+		t.Errorf("Expected 5001 candles but got %d", len(candles))
+	}
+	if len(gaps) != 0 {
+		t.Errorf("Expected no gaps but got %v", gaps)
+	}
+}
+
+func TestRangeMisalignedStart(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+	r.HandleFunc("/assets/bitcoin/history", seriesHandler(t, time.Hour, 2000, Gap{}, historyPoint))
+	r.HandleFunc("/candles", seriesHandler(t, 5*time.Minute, 2000, Gap{}, func(ms int64) string {
+		return fmt.Sprintf(`{"open":"1","high":"2","low":"0.5","close":"1.5","volume":"10","period":%d}`, ms)
+	}))
+
+	aligned := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	start := aligned.Add(150 * time.Second)
+
+	history, _, err := client.AssetHistoryRange(context.Background(), "bitcoin", Hour, start, aligned.Add(3*time.Hour), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 4 || !history[0].Time.Equal(aligned) {
+		t.Errorf("Expected 4 points from the start of the first hour but got %d starting %v", len(history), history[0].Time)
+	}
+
+	// the same first candle as Candles returns for the start
+	params := &CandlesRequest{ExchangeID: "poloniex", BaseID: "ethereum", QuoteID: "bitcoin", Interval: FiveMinutes}
+	candles, gaps, err := client.CandlesRange(context.Background(), params, start, aligned.Add(time.Hour), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(candles) != 13 || !candles[0].Period.Equal(aligned) {
+		t.Errorf("Expected 13 candles from the start of the first one but got %d starting %v", len(candles), candles[0].Period)
+	}
+	if len(gaps) != 0 {
+		t.Errorf("Expected no gaps but got %v", gaps)
+	}
+}
+
+func TestFindGaps(t *testing.T) {
+	start := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time {
+		return start.Add(time.Duration(minutes) * time.Minute)
+	}
+
+	tests := []struct {
+		name  string
+		times []time.Time
+		gaps  []Gap
+	}{
+		{"complete", []time.Time{at(0), at(1), at(2), at(3)}, nil},
+		{"empty", nil, []Gap{{at(0), at(3)}}},
+		{"leading", []time.Time{at(2), at(3)}, []Gap{{at(0), at(2)}}},
+		{"middle", []time.Time{at(0), at(3)}, []Gap{{at(1), at(3)}}},
+		{"trailing", []time.Time{at(0), at(1)}, []Gap{{at(2), at(3)}}},
+	}
+	for _, test := range tests {
+		gaps := findGaps(test.times, at(0), at(3), time.Minute)
+		if fmt.Sprint(gaps) != fmt.Sprint(test.gaps) {
+			t.Errorf("%s: expected gaps %v but got %v", test.name, test.gaps, gaps)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package coincap
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Request quotas published by CoinCap
+const (
+	AnonymousRequestsPerMinute = 200 // quota for clients without an API key
+	KeyedRequestsPerMinute     = 500 // quota for clients sending an API key
+)
+
+// RateLimiter is a token bucket that keeps every goroutine sharing a Client
+// collectively under the CoinCap request quota. It refills continuously at the
+// configured rate and adapts to the X-Ratelimit-Limit and X-Ratelimit-Remaining
+// headers returned by the API. It is safe for concurrent use
+type RateLimiter struct {
+	mu     sync.Mutex
+	clock  clock
+	limit  int       // requests allowed per minute
+	burst  int       // maximum number of requests that can be made at once
+	tokens float64   // currently available requests, negative when callers are queued
+	last   time.Time // last time tokens were refilled
+
+	serverLimit     int // last X-Ratelimit-Limit seen, -1 if never seen
+	serverRemaining int // last X-Ratelimit-Remaining seen, -1 if never seen
+}
+
+// RateLimitBudget is a snapshot of a RateLimiter's state for monitoring
+type RateLimitBudget struct {
+	Limit           int     // requests allowed per minute
+	Burst           int     // maximum number of requests that can be made at once
+	Available       float64 // requests that can be made right now without waiting
+	ServerLimit     int     // last X-Ratelimit-Limit reported by the API, -1 if unknown
+	ServerRemaining int     // last X-Ratelimit-Remaining reported by the API, -1 if unknown
+}
+
+// NewRateLimiter returns a limiter allowing requestsPerMinute requests with bursts of up to burst requests.
+// A burst below 1 defaults to requestsPerMinute
+func NewRateLimiter(requestsPerMinute, burst int) *RateLimiter {
+	if requestsPerMinute < 1 {
+		requestsPerMinute = 1
+	}
+	if burst < 1 {
+		burst = requestsPerMinute
+	}
+	return &RateLimiter{
+		clock:           realClock{},
+		limit:           requestsPerMinute,
+		burst:           burst,
+		tokens:          float64(burst),
+		serverLimit:     -1,
+		serverRemaining: -1,
+	}
+}
+
+// Wait blocks until a request may be made or the context is done
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// reserve a token, possibly going into debt, so queued callers are served in order
+	l.mu.Lock()
+	l.refill()
+	l.tokens--
+	var wait time.Duration
+	if l.tokens < 0 {
+		wait = time.Duration(math.Ceil(-l.tokens / l.ratePerNano()))
+	}
+	l.mu.Unlock()
+
+	if err := sleep(ctx, l.clock, wait); err != nil {
+		// hand the reservation back to the other callers, without overfilling
+		// the bucket if it refilled in the meantime
+		l.mu.Lock()
+		l.refill()
+		l.tokens = math.Min(l.tokens+1, float64(l.burst))
+		l.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Budget returns the current state of the limiter
+func (l *RateLimiter) Budget() RateLimitBudget {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill()
+
+	available := l.tokens
+	if available < 0 {
+		available = 0
+	}
+	return RateLimitBudget{
+		Limit:           l.limit,
+		Burst:           l.burst,
+		Available:       available,
+		ServerLimit:     l.serverLimit,
+		ServerRemaining: l.serverRemaining,
+	}
+}
+
+// Update adapts the limiter to the rate limit headers of an API response.
+// A changed X-Ratelimit-Limit becomes the new refill rate and the available
+// budget never exceeds X-Ratelimit-Remaining, as other processes may share the quota
+func (l *RateLimiter) Update(header http.Header) {
+	limit, limitErr := strconv.Atoi(header.Get("X-Ratelimit-Limit"))
+	remaining, remainingErr := strconv.Atoi(header.Get("X-Ratelimit-Remaining"))
+	if limitErr != nil && remainingErr != nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill()
+
+	if limitErr == nil && limit > 0 {
+		l.serverLimit = limit
+		if limit != l.limit {
+			l.limit = limit
+			if l.burst > limit {
+				l.burst = limit
+			}
+		}
+	}
+	if remainingErr == nil && remaining >= 0 {
+		l.serverRemaining = remaining
+		if float64(remaining) < l.tokens {
+			l.tokens = float64(remaining)
+		}
+	}
+}
+
+// refill adds the tokens accumulated since the last refill. l.mu must be held
+func (l *RateLimiter) refill() {
+	now := l.clock.Now()
+	if l.last.IsZero() {
+		l.last = now
+		return
+	}
+	elapsed := now.Sub(l.last)
+	if elapsed <= 0 {
+		return
+	}
+	l.last = now
+	l.tokens += float64(elapsed) * l.ratePerNano()
+	if l.tokens > float64(l.burst) {
+		l.tokens = float64(l.burst)
+	}
+}
+
+// ratePerNano returns the refill rate in tokens per nanosecond. l.mu must be held
+func (l *RateLimiter) ratePerNano() float64 {
+	return float64(l.limit) / float64(time.Minute)
+}
@@ -0,0 +1,153 @@
+package coincap
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterBurstAndRefill(t *testing.T) {
+	clk := newFakeClock()
+	limiter := NewRateLimiter(60, 2)
+	limiter.clock = clk
+
+	// the burst is available right away, the third request waits for a refill
+	for i := 0; i < 3; i++ {
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sleeps := clk.Sleeps()
+	if len(sleeps) != 1 || sleeps[0] != time.Second {
+		t.Errorf("Expected a single 1s wait, got %v", sleeps)
+	}
+
+	budget := limiter.Budget()
+	if budget.Limit != 60 || budget.Burst != 2 || budget.Available != 0 {
+		t.Errorf("Unexpected budget after draining the bucket: %+v", budget)
+	}
+}
+
+func TestRateLimiterContextCanceled(t *testing.T) {
+	limiter := NewRateLimiter(1, 1)
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected the wait to be abandoned with the context, got: %v", err)
+	}
+}
+
+// manualClock only moves when told to and never fires its timers
+type manualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (m *manualClock) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.now
+}
+
+func (m *manualClock) After(d time.Duration) <-chan time.Time { return nil }
+
+func (m *manualClock) Advance(d time.Duration) {
+	m.mu.Lock()
+	m.now = m.now.Add(d)
+	m.mu.Unlock()
+}
+
+func TestRateLimiterRefundDoesNotOverfill(t *testing.T) {
+	clk := &manualClock{now: time.Unix(1536336916, 0)}
+	limiter := NewRateLimiter(60, 1)
+	limiter.clock = clk
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// the bucket refills completely while the second caller waits and gives up
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- limiter.Wait(ctx) }()
+	waitFor(t, "the caller to queue", func() bool { return queued(limiter) })
+	clk.Advance(10 * time.Minute)
+	if available := limiter.Budget().Available; available != 1 {
+		t.Fatalf("Expected the bucket to refill to its burst of 1 but %v are available", available)
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("Expected the wait to be abandoned, got: %v", err)
+	}
+
+	if available := limiter.Budget().Available; available != 1 {
+		t.Errorf("Expected the refund to be capped at the burst of 1 but %v are available", available)
+	}
+}
+
+// queued reports whether a caller has reserved a token it is waiting for
+func queued(l *RateLimiter) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.tokens < 0
+}
+
+func TestRateLimiterUpdateFromHeaders(t *testing.T) {
+	limiter := NewRateLimiter(AnonymousRequestsPerMinute, 0)
+	limiter.clock = newFakeClock()
+
+	header := http.Header{}
+	header.Set("X-Ratelimit-Limit", "100")
+	header.Set("X-Ratelimit-Remaining", "3")
+	limiter.Update(header)
+
+	budget := limiter.Budget()
+	if budget.Limit != 100 || budget.Burst != 100 {
+		t.Errorf("Expected limit and burst to adapt to 100, got %+v", budget)
+	}
+	if budget.Available != 3 || budget.ServerRemaining != 3 || budget.ServerLimit != 100 {
+		t.Errorf("Expected available budget to be capped by the server, got %+v", budget)
+	}
+}
+
+func TestRateLimiterSharedByClient(t *testing.T) {
+	clk := newFakeClock()
+	limiter := NewRateLimiter(60, 1)
+	limiter.clock = clk
+
+	teardown := setup(WithRateLimiter(limiter))
+	defer teardown()
+
+	r.HandleFunc("/rates/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Ratelimit-Limit", "60")
+		w.Header().Set("X-Ratelimit-Remaining", "50")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, fixture("ratesByID.json"))
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := client.RateByID("bitcoin"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// one request fits in the burst, every other one had to wait
+	if n := len(clk.Sleeps()); n != 4 {
+		t.Errorf("Expected 4 requests to wait for the limiter but %d did", n)
+	}
+	if budget := limiter.Budget(); budget.ServerRemaining != 50 {
+		t.Errorf("Expected server budget to be tracked, got %+v", budget)
+	}
+}
@@ -1,37 +1,48 @@
 package coincap
 
 import (
-	"encoding/json"
+	"context"
 	"net/http"
 )
 
 // Rate contains the exchange rate of a given asset in terms of USD as well as
 // common identifiers for the asset in question and whether or not it is a fiat currency
 type Rate struct {
-	ID             string `json:"id"`             // unique identifier for asset or fiat
-	Symbol         string `json:"symbol"`         // most common symbol used to identify asset or fiat
-	CurrencySymbol string `json:"currencySymbol"` // currency symbol if available
-	RateUSD        string `json:"rateUsd"`        // rate conversion to USD
-	Type           string `json:"type"`           // type of currency - fiat or crypto
+	ID             string     `json:"id"`             // unique identifier for asset or fiat
+	Symbol         string     `json:"symbol"`         // most common symbol used to identify asset or fiat
+	CurrencySymbol NullString `json:"currencySymbol"` // currency symbol, null if there is none
+	RateUSD        string     `json:"rateUsd"`        // rate conversion to USD
+	Type           string     `json:"type"`           // type of currency - fiat or crypto
+}
+
+// RateUSDDecimal parses RateUSD as an exact Decimal
+func (r *Rate) RateUSDDecimal() (Decimal, error) {
+	return parseDecimalField("rateUsd", r.RateUSD)
 }
 
 // Rates returns currency rates standardized in USD.
 // Fiat rates are sourced from OpenExchangeRates.org
 func (c *Client) Rates() ([]*Rate, *Timestamp, error) {
-	req, err := http.NewRequest("GET", c.baseURL+"/rates", nil)
+	return c.RatesWithContext(context.Background())
+}
+
+// RatesWithContext is like Rates but carries ctx through the request,
+// allowing the caller to set deadlines or cancel the call
+func (c *Client) RatesWithContext(ctx context.Context) ([]*Rate, *Timestamp, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/rates", nil)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	// make the request
-	ccResp, err := c.fetchAndParse(req)
+	ccResp, err := c.fetchAndParse("/rates", req)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	// Unmarshal the deferred json from the data field
 	var rates []*Rate
-	if err := json.Unmarshal(*ccResp.Data, &rates); err != nil {
+	if err := decodeData("/rates", ccResp, &rates); err != nil {
 		return nil, nil, err
 	}
 
@@ -40,20 +51,28 @@ func (c *Client) Rates() ([]*Rate, *Timestamp, error) {
 
 // RateByID returns the USD rate for the given asset identifier
 func (c *Client) RateByID(id string) (*Rate, *Timestamp, error) {
-	req, err := http.NewRequest("GET", c.baseURL+"/rates/"+id, nil)
+	return c.RateByIDWithContext(context.Background(), id)
+}
+
+// RateByIDWithContext is like RateByID but carries ctx through the request,
+// allowing the caller to set deadlines or cancel the call
+func (c *Client) RateByIDWithContext(ctx context.Context, id string) (*Rate, *Timestamp, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.baseURL+"/rates/"+id, nil)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	// make the request
-	ccResp, err := c.fetchAndParse(req)
+	ccResp, err := c.fetchAndParse("/rates/{id}", req)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	// Unmarshal the deferred json from the data field
 	var rate Rate
-	json.Unmarshal(*ccResp.Data, &rate)
+	if err := decodeData("/rates/{id}", ccResp, &rate); err != nil {
+		return nil, nil, err
+	}
 
 	return &rate, ccResp.Timestamp, nil
 }
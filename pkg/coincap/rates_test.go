@@ -16,12 +16,11 @@ var (
 	client *Client
 )
 
-func setup() func() {
+func setup(opts ...Option) func() {
 	r = mux.NewRouter()
 	server = httptest.NewServer(r)
 
-	client = NewClient(nil)
-	client.SetBaseURL(server.URL)
+	client = NewClient(nil, append([]Option{WithBaseURL(server.URL)}, opts...)...)
 
 	return func() {
 		server.Close()
@@ -58,12 +57,12 @@ func TestRates(t *testing.T) {
 	expected := Rate{
 		ID:             "romanian-leu",
 		Symbol:         "RON",
-		CurrencySymbol: "lei",
+		CurrencySymbol: NewNullString("lei"),
 		Type:           "fiat",
 		RateUSD:        "0.2505529076289101",
 	}
 	if *got != expected {
-		t.Errorf("Expected %s, Got %s", expected, got)
+		t.Errorf("Expected %+v, Got %+v", expected, got)
 	}
 }
 
@@ -100,12 +99,12 @@ func TestRateByID(t *testing.T) {
 	expected := Rate{
 		ID:             "bitcoin",
 		Symbol:         "BTC",
-		CurrencySymbol: "₿",
+		CurrencySymbol: NewNullString("₿"),
 		Type:           "crypto",
 		RateUSD:        "6460.9771089680171173",
 	}
 	if *rate != expected {
-		t.Errorf("Expected %s, Got %s", expected, rate)
+		t.Errorf("Expected %+v, Got %+v", expected, rate)
 	}
 }
 
@@ -140,3 +139,12 @@ func TestRatesNoTimestamp(t *testing.T) {
 		t.Errorf("Expected error due to missing timestamp json %s", timestamp)
 	}
 }
+
+func TestRateByIDMalformed(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+	serveFixture("/rates/{id}", "ratesByID_malformed.json")
+
+	_, _, err := client.RateByID("bitcoin")
+	assertDecodeError(t, err, "/rates/{id}")
+}
@@ -0,0 +1,119 @@
+package coincap
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ReconnectPolicy configures how a realtime stream detects dead connections and recovers from them.
+// Streams without a ReconnectPolicy stop as soon as their connection fails
+type ReconnectPolicy struct {
+	MaxAttempts int           // consecutive failed reconnection attempts before giving up. 0 means never give up
+	BaseBackoff time.Duration // delay before the first reconnection attempt, doubled for each subsequent one
+	MaxBackoff  time.Duration // upper bound for the computed delay (0 means no bound)
+	Jitter      float64       // fraction (0 to 1) of each delay that is randomized to spread out reconnections
+
+	// ReadTimeout is how long a connection may go without receiving anything, including pongs,
+	// before it is considered dead. 0 disables the check
+	ReadTimeout time.Duration
+	// PingInterval is how often pings are sent to keep the connection alive and to provoke
+	// the pongs ReadTimeout relies on. 0 disables pings
+	PingInterval time.Duration
+}
+
+// DefaultReconnectPolicy returns a policy for long running services that never gives up,
+// pings every 15 seconds and treats a connection silent for 45 seconds as dead
+func DefaultReconnectPolicy() *ReconnectPolicy {
+	return &ReconnectPolicy{
+		BaseBackoff:  time.Second,
+		MaxBackoff:   time.Minute,
+		Jitter:       0.2,
+		ReadTimeout:  45 * time.Second,
+		PingInterval: 15 * time.Second,
+	}
+}
+
+// backoff returns the delay before the given (1 indexed) reconnection attempt
+func (p *ReconnectPolicy) backoff(attempt int) time.Duration {
+	retry := RetryPolicy{BaseBackoff: p.BaseBackoff, MaxBackoff: p.MaxBackoff, Jitter: p.Jitter}
+	return retry.backoff(attempt)
+}
+
+// StreamGap reports that a stream lost its connection and reconnected, or replaced its
+// connection because the assets it subscribes to changed.
+// Updates published between DisconnectedAt and ReconnectedAt were missed
+type StreamGap struct {
+	DisconnectedAt time.Time // time the connection was found to be lost
+	ReconnectedAt  time.Time // time the new connection was established
+	Attempts       int       // reconnection attempts it took
+	Err            error     // error the connection was lost with
+}
+
+// Duration returns how long the stream was disconnected
+func (g StreamGap) Duration() time.Duration {
+	return g.ReconnectedAt.Sub(g.DisconnectedAt)
+}
+
+// isPermanentDialError reports whether reconnecting after err is pointless, e.g. because
+// the api key was rejected or the feed does not exist
+func isPermanentDialError(err error) bool {
+	return errors.Is(err, ErrBadRequest) || errors.Is(err, ErrUnauthorized) || errors.Is(err, ErrNotFound)
+}
+
+// redial connects again to the stream's feed, backing off between failed attempts
+func (s *stream) redial(ctx context.Context) (*websocket.Conn, int, error) {
+	for attempt := 1; ; attempt++ {
+		if err := sleep(ctx, s.clock, s.reconnect.backoff(attempt)); err != nil {
+			return nil, attempt, err
+		}
+		path, query := s.target()
+		conn, err := s.client.dialWebsocket(ctx, path, query)
+		if err == nil {
+			return conn, attempt, nil
+		}
+		s.client.logf("coincap: Reconnecting to %s failed on attempt %d: %v", path, attempt, err)
+		if ctx.Err() != nil || isPermanentDialError(err) || (s.reconnect.MaxAttempts > 0 && attempt >= s.reconnect.MaxAttempts) {
+			return nil, attempt, err
+		}
+	}
+}
+
+// extendDeadline gives conn another ReadTimeout to receive something before reads fail
+func (s *stream) extendDeadline(conn *websocket.Conn) {
+	if s.reconnect != nil && s.reconnect.ReadTimeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(s.reconnect.ReadTimeout))
+	}
+}
+
+// keepAlive counts pongs as signs of life and pings the server every PingInterval until
+// stopped is closed, so a dead connection fails its reads once ReadTimeout passes
+func (s *stream) keepAlive(conn *websocket.Conn, stopped <-chan struct{}) {
+	if s.reconnect == nil {
+		return
+	}
+	conn.SetPongHandler(func(string) error {
+		s.extendDeadline(conn)
+		return nil
+	})
+
+	interval := s.reconnect.PingInterval
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				// a failed ping surfaces as a failed read soon enough
+				conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(interval))
+			case <-stopped:
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,212 @@
+package coincap
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// connCounter hands out 1 indexed connection numbers to a test server's handlers
+type connCounter struct {
+	mu sync.Mutex
+	n  int
+}
+
+func (c *connCounter) next() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.n++
+	return c.n
+}
+
+// receiveGap returns the next gap or fails the test after a second
+func receiveGap(t *testing.T, gaps <-chan StreamGap) StreamGap {
+	t.Helper()
+	select {
+	case gap, ok := <-gaps:
+		if !ok {
+			t.Fatal("Expected a gap but the stream stopped")
+		}
+		return gap
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for a gap")
+	}
+	return StreamGap{}
+}
+
+func TestStreamReconnects(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+	client.clock = newFakeClock()
+
+	var conns connCounter
+	serveWebsocket("/prices", func(conn *websocket.Conn, req *http.Request) {
+		if assets := req.URL.Query().Get("assets"); assets != "bitcoin,ethereum" {
+			t.Errorf("Expected every connection to subscribe to bitcoin,ethereum but got %q", assets)
+		}
+		if conns.next() == 1 {
+			// drop the connection without a close handshake
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"bitcoin":"1"}`))
+			conn.UnderlyingConn().Close()
+			return
+		}
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"bitcoin":"2"}`))
+		drain(conn)
+	})
+
+	policy := &ReconnectPolicy{BaseBackoff: 5 * time.Second}
+	stream, err := client.PriceStreamWithOptions(context.Background(), &StreamOptions{Reconnect: policy}, "bitcoin", "ethereum")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	if u := receive(t, stream.Updates()); u.PriceUSD != "1" {
+		t.Errorf("Expected the first price from the first connection but got %s", u.PriceUSD)
+	}
+	gap := receiveGap(t, stream.Gaps())
+	if gap.Attempts != 1 || gap.Duration() != 5*time.Second || gap.Err == nil {
+		t.Errorf("Expected a 5s gap after one attempt with the cause, got %+v", gap)
+	}
+	if u := receive(t, stream.Updates()); u.PriceUSD != "2" {
+		t.Errorf("Expected the second price from the new connection but got %s", u.PriceUSD)
+	}
+	if stream.Err() != nil {
+		t.Errorf("Expected the stream to heal but got %v", stream.Err())
+	}
+}
+
+func TestStreamDeadConnection(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+	client.clock = newFakeClock()
+
+	var conns connCounter
+	release := make(chan struct{})
+	defer close(release)
+	serveWebsocket("/trades/binance", func(conn *websocket.Conn, req *http.Request) {
+		if conns.next() == 1 {
+			// stop reading, which also stops answering pings, as a hung server would
+			<-release
+			return
+		}
+		conn.WriteMessage(websocket.TextMessage, tradeN(2))
+		drain(conn)
+	})
+
+	policy := &ReconnectPolicy{ReadTimeout: 100 * time.Millisecond, PingInterval: 20 * time.Millisecond}
+	stream, err := client.TradeStream(context.Background(), "binance", &StreamOptions{Reconnect: policy})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	gap := receiveGap(t, stream.Gaps())
+	var netErr interface{ Timeout() bool }
+	if !errors.As(gap.Err, &netErr) || !netErr.Timeout() {
+		t.Errorf("Expected the connection to time out but got %v", gap.Err)
+	}
+	if trade := receiveTrade(t, stream.Trades()); trade.Volume.String() != "2" {
+		t.Errorf("Expected a trade from the new connection but got %+v", trade)
+	}
+}
+
+func TestStreamPongsKeepAlive(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	var conns connCounter
+	serveWebsocket("/prices", func(conn *websocket.Conn, req *http.Request) {
+		conns.next()
+		// reading answers pings with pongs
+		drain(conn)
+	})
+
+	policy := &ReconnectPolicy{ReadTimeout: 100 * time.Millisecond, PingInterval: 20 * time.Millisecond}
+	stream, err := client.PriceStreamWithOptions(context.Background(), &StreamOptions{Reconnect: policy}, "bitcoin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	select {
+	case gap := <-stream.Gaps():
+		t.Errorf("Expected a quiet but healthy connection to be kept, got %+v", gap)
+	case <-time.After(300 * time.Millisecond):
+	}
+	if n := conns.next() - 1; n != 1 {
+		t.Errorf("Expected a single connection but got %d", n)
+	}
+}
+
+func TestStreamReconnectGivesUp(t *testing.T) {
+	tests := []struct {
+		status   int
+		sentinel error
+		attempts int
+	}{
+		{http.StatusServiceUnavailable, ErrServerError, 3},
+		{http.StatusForbidden, ErrUnauthorized, 1},
+	}
+	for _, test := range tests {
+		teardown := setup()
+		clk := newFakeClock()
+		client.clock = clk
+
+		var conns connCounter
+		serveWebsocket("/prices", func(conn *websocket.Conn, req *http.Request) {
+			conn.UnderlyingConn().Close()
+		})
+		// every connection after the first is refused
+		r.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if conns.next() > 1 {
+					w.WriteHeader(test.status)
+					return
+				}
+				next.ServeHTTP(w, req)
+			})
+		})
+
+		policy := &ReconnectPolicy{MaxAttempts: 3, BaseBackoff: time.Second}
+		stream, err := client.PriceStreamWithOptions(context.Background(), &StreamOptions{Reconnect: policy}, "bitcoin")
+		if err != nil {
+			t.Fatal(err)
+		}
+		waitClosed(t, stream.Updates())
+
+		if !errors.Is(stream.Err(), test.sentinel) {
+			t.Errorf("%d: expected the stream to stop with %v but got %v", test.status, test.sentinel, stream.Err())
+		}
+		if sleeps := clk.Sleeps(); len(sleeps) != test.attempts {
+			t.Errorf("%d: expected %d reconnection attempts but got %d", test.status, test.attempts, len(sleeps))
+		}
+		teardown()
+	}
+}
+
+func TestStreamWithoutReconnectStops(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	serveWebsocket("/prices", func(conn *websocket.Conn, req *http.Request) {
+		conn.UnderlyingConn().Close()
+	})
+
+	stream, err := client.PriceStream(context.Background(), "bitcoin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitClosed(t, stream.Updates())
+	if stream.Err() == nil {
+		t.Errorf("Expected the dropped connection to be reported")
+	}
+	if _, ok := <-stream.Gaps(); ok {
+		t.Errorf("Expected no gaps without a reconnect policy")
+	}
+}
@@ -0,0 +1,191 @@
+package coincap
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy configures if and how failed requests are retried.
+// Retries are opt-in, a client without a RetryPolicy makes every request exactly once.
+type RetryPolicy struct {
+	MaxAttempts int           // total attempts including the first one. Values below 2 disable retries
+	BaseBackoff time.Duration // delay before the first retry, doubled for each subsequent attempt
+	MaxBackoff  time.Duration // upper bound for the computed delay and for a server's Retry-After (0 means no bound)
+	Jitter      float64       // fraction (0 to 1) of each delay that is randomized to spread out retries
+
+	// RetryableStatusCodes lists the response codes that trigger a retry.
+	// If nil, DefaultRetryableStatusCodes is used
+	RetryableStatusCodes []int
+
+	// RetryableError reports whether a transport error should trigger a retry.
+	// If nil, timeouts, connection resets and unexpected EOFs are retried
+	RetryableError func(err error) bool
+}
+
+// DefaultRetryableStatusCodes are the status codes retried when a RetryPolicy does not specify any
+var DefaultRetryableStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// DefaultRetryPolicy returns a policy suitable for batch jobs that retries
+// transient failures up to 4 times with exponential backoff starting at 500ms
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 4,
+		BaseBackoff: 500 * time.Millisecond,
+		MaxBackoff:  30 * time.Second,
+		Jitter:      0.2,
+	}
+}
+
+// shouldRetry decides if the given attempt should be retried and how long to wait first.
+// Exactly one of resp or err is expected to be set
+func (p *RetryPolicy) shouldRetry(attempt int, resp *http.Response, err error, now time.Time) (time.Duration, bool) {
+	if p == nil || attempt >= p.MaxAttempts {
+		return 0, false
+	}
+
+	if err != nil {
+		// never retry because the caller gave up
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return 0, false
+		}
+		retryable := p.RetryableError
+		if retryable == nil {
+			retryable = isTransientError
+		}
+		if !retryable(err) {
+			return 0, false
+		}
+		return p.backoff(attempt), true
+	}
+
+	codes := p.RetryableStatusCodes
+	if codes == nil {
+		codes = DefaultRetryableStatusCodes
+	}
+	for _, code := range codes {
+		if resp.StatusCode != code {
+			continue
+		}
+		// the server knows best how long we should wait
+		if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now); ok {
+			return p.limit(wait), true
+		}
+		return p.backoff(attempt), true
+	}
+	return 0, false
+}
+
+// backoff returns the delay before retrying after the given (1 indexed) attempt
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseBackoff
+	for i := 1; i < attempt; i++ {
+		// stop doubling before the delay overflows
+		if d > maxDuration/2 {
+			d = maxDuration
+			break
+		}
+		d *= 2
+		if p.MaxBackoff > 0 && d > p.MaxBackoff {
+			break
+		}
+	}
+	d = p.limit(d)
+	if p.Jitter > 0 && d > 0 {
+		jitter := p.Jitter
+		if jitter > 1 {
+			jitter = 1
+		}
+		d -= time.Duration(rand.Float64() * jitter * float64(d))
+	}
+	return d
+}
+
+// limit caps d at MaxBackoff if the policy has one
+func (p *RetryPolicy) limit(d time.Duration) time.Duration {
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return d
+}
+
+// maxDuration is the longest representable time.Duration
+const maxDuration = time.Duration(1<<63 - 1)
+
+// parseRetryAfter parses the value of a Retry-After header which
+// is either a number of seconds or an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	// values too large for an int64 come back as the largest one along with ErrRange
+	if secs, err := strconv.ParseInt(value, 10, 64); err == nil || errors.Is(err, strconv.ErrRange) {
+		if secs < 0 {
+			return 0, false
+		}
+		if secs > int64(maxDuration/time.Second) {
+			return maxDuration, true
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		wait := at.Sub(now)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
+
+// isTransientError reports whether a transport error is likely to go away on its own
+func isTransientError(err error) bool {
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return false
+}
+
+// clock abstracts the passage of time so retries can be tested without sleeping
+type clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock is the clock backed by the time package
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// sleep waits for d to pass on the clock or returns early with the context's error
+func sleep(ctx context.Context, clk clock, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if d <= 0 {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-clk.After(d):
+		return nil
+	}
+}
@@ -0,0 +1,229 @@
+package coincap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock records requested sleeps and fires them immediately
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(1536336916, 0)}
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) After(d time.Duration) <-chan time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sleeps = append(f.sleeps, d)
+	f.now = f.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- f.now
+	return ch
+}
+
+func (f *fakeClock) Sleeps() []time.Duration {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]time.Duration(nil), f.sleeps...)
+}
+
+// failingHandler responds with the given statuses in order and then succeeds with the fixture
+func failingHandler(fixtureName string, header http.Header, statuses ...int) (http.HandlerFunc, *int) {
+	calls := 0
+	return func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls <= len(statuses) {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(statuses[calls-1])
+			fmt.Fprint(w, `{"error":"try again later"}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, fixture(fixtureName))
+	}, &calls
+}
+
+func TestRetryExponentialBackoff(t *testing.T) {
+	teardown := setup(WithRetryPolicy(&RetryPolicy{
+		MaxAttempts: 4,
+		BaseBackoff: 100 * time.Millisecond,
+		MaxBackoff:  300 * time.Millisecond,
+	}))
+	defer teardown()
+
+	clk := newFakeClock()
+	client.clock = clk
+
+	handler, calls := failingHandler("rates.json", nil, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusBadGateway)
+	r.HandleFunc("/rates", handler)
+
+	if _, _, err := client.Rates(); err != nil {
+		t.Fatal(err)
+	}
+	if *calls != 4 {
+		t.Errorf("Expected 4 attempts but got %d", *calls)
+	}
+
+	expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond}
+	sleeps := clk.Sleeps()
+	if fmt.Sprint(sleeps) != fmt.Sprint(expected) {
+		t.Errorf("Expected backoff of %v, got %v", expected, sleeps)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	teardown := setup(WithRetryPolicy(&RetryPolicy{MaxAttempts: 2, BaseBackoff: time.Second}))
+	defer teardown()
+
+	client.clock = newFakeClock()
+
+	handler, calls := failingHandler("rates.json", nil, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway)
+	r.HandleFunc("/rates", handler)
+
+	_, _, err := client.Rates()
+	if !errors.Is(err, ErrServerError) {
+		t.Errorf("Expected the last server error to be returned but got: %v", err)
+	}
+	if *calls != 2 {
+		t.Errorf("Expected 2 attempts but got %d", *calls)
+	}
+}
+
+func TestRetryAfterHeader(t *testing.T) {
+	teardown := setup(WithRetryPolicy(&RetryPolicy{MaxAttempts: 3, BaseBackoff: time.Millisecond}))
+	defer teardown()
+
+	clk := newFakeClock()
+	client.clock = clk
+
+	header := http.Header{"Retry-After": []string{"7"}}
+	handler, _ := failingHandler("rates.json", header, http.StatusTooManyRequests)
+	r.HandleFunc("/rates", handler)
+
+	if _, _, err := client.Rates(); err != nil {
+		t.Fatal(err)
+	}
+	sleeps := clk.Sleeps()
+	if len(sleeps) != 1 || sleeps[0] != 7*time.Second {
+		t.Errorf("Expected a single 7s wait from Retry-After, got %v", sleeps)
+	}
+}
+
+func TestRetryAfterHeaderIsCapped(t *testing.T) {
+	teardown := setup(WithRetryPolicy(&RetryPolicy{MaxAttempts: 3, BaseBackoff: time.Millisecond, MaxBackoff: 30 * time.Second}))
+	defer teardown()
+
+	clk := newFakeClock()
+	client.clock = clk
+
+	// a day, as a misbehaving server might ask for
+	header := http.Header{"Retry-After": []string{"86400"}}
+	handler, _ := failingHandler("rates.json", header, http.StatusTooManyRequests)
+	r.HandleFunc("/rates", handler)
+
+	if _, _, err := client.Rates(); err != nil {
+		t.Fatal(err)
+	}
+	sleeps := clk.Sleeps()
+	if len(sleeps) != 1 || sleeps[0] != 30*time.Second {
+		t.Errorf("Expected Retry-After to be capped at 30s, got %v", sleeps)
+	}
+}
+
+func TestRetryBackoffDoesNotOverflow(t *testing.T) {
+	unbounded := &RetryPolicy{BaseBackoff: time.Second}
+	for _, attempt := range []int{40, 64, 100, 1000} {
+		if d := unbounded.backoff(attempt); d <= 0 {
+			t.Errorf("Expected a positive delay for attempt %d but got %v", attempt, d)
+		}
+	}
+	bounded := &RetryPolicy{BaseBackoff: time.Second, MaxBackoff: time.Minute}
+	if d := bounded.backoff(1000); d != time.Minute {
+		t.Errorf("Expected the delay to be capped at a minute but got %v", d)
+	}
+}
+
+func TestRetryNonRetryableStatus(t *testing.T) {
+	teardown := setup(WithRetryPolicy(&RetryPolicy{MaxAttempts: 3}))
+	defer teardown()
+
+	client.clock = newFakeClock()
+
+	handler, calls := failingHandler("rates.json", nil, http.StatusNotFound)
+	r.HandleFunc("/rates", handler)
+
+	_, _, err := client.Rates()
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected not found error but got: %v", err)
+	}
+	if *calls != 1 {
+		t.Errorf("Expected 404 not to be retried but got %d attempts", *calls)
+	}
+}
+
+func TestRetryStopsWhenContextDone(t *testing.T) {
+	teardown := setup(WithRetryPolicy(&RetryPolicy{MaxAttempts: 5}))
+	defer teardown()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client.clock = newFakeClock()
+
+	calls := 0
+	r.HandleFunc("/rates", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		cancel()
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	_, _, err := client.RatesWithContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context canceled but got: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected no retries after cancellation but got %d attempts", calls)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		wait  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"3", 3 * time.Second, true},
+		{"-1", 0, false},
+		{"99999999999999999", maxDuration, true},
+		{"99999999999999999999999", maxDuration, true}, // beyond int64
+		{"-99999999999999999999999", 0, false},
+		{"Tue, 01 Jan 2019 00:00:10 GMT", 10 * time.Second, true},
+		{"Mon, 31 Dec 2018 23:59:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+	for _, tt := range tests {
+		wait, ok := parseRetryAfter(tt.value, now)
+		if wait != tt.wait || ok != tt.ok {
+			t.Errorf("parseRetryAfter(%q) = %v, %v; expected %v, %v", tt.value, wait, ok, tt.wait, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package coincap
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Value implements driver.Valuer. Timestamps are stored as time.Time and
+// the zero Timestamp as NULL
+func (t Timestamp) Value() (driver.Value, error) {
+	if t.IsZero() {
+		return nil, nil
+	}
+	return t.Time, nil
+}
+
+// Scan implements sql.Scanner. It accepts time.Time, integer or fractional unix milliseconds
+// and text holding either milliseconds or an RFC 3339 time. NULL leaves the Timestamp zero
+func (t *Timestamp) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		t.Time = time.Time{}
+	case time.Time:
+		t.Time = v
+	case int64:
+		t.Time = fromMillis(v)
+	case float64:
+		return t.UnmarshalText([]byte(strconv.FormatFloat(v, 'f', -1, 64)))
+	case []byte:
+		return t.scanText(string(v))
+	case string:
+		return t.scanText(v)
+	default:
+		return fmt.Errorf("Cannot scan %T into Timestamp", src)
+	}
+	return nil
+}
+
+// scanText parses milliseconds or, as some drivers return times as text, an RFC 3339 time
+func (t *Timestamp) scanText(s string) error {
+	s = strings.TrimSpace(s)
+	if parsed, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		t.Time = parsed
+		return nil
+	}
+	return t.UnmarshalText([]byte(s))
+}
+
+// Value implements driver.Valuer. Decimals are stored as their exact string
+// representation, which numeric and decimal columns accept, and null as NULL
+func (d Decimal) Value() (driver.Value, error) {
+	if d.null {
+		return nil, nil
+	}
+	return d.String(), nil
+}
+
+// Scan implements sql.Scanner. It accepts numeric text, integers and floats. NULL is scanned as a null Decimal
+func (d *Decimal) Scan(src interface{}) error {
+	var (
+		parsed Decimal
+		err    error
+	)
+	switch v := src.(type) {
+	case nil:
+		parsed = NullDecimal()
+	case int64:
+		parsed = NewDecimal(v, 0)
+	case float64:
+		parsed, err = ParseDecimal(strconv.FormatFloat(v, 'f', -1, 64))
+	case []byte:
+		parsed, err = ParseDecimal(strings.TrimSpace(string(v)))
+	case string:
+		parsed, err = ParseDecimal(strings.TrimSpace(v))
+	default:
+		return fmt.Errorf("Cannot scan %T into Decimal", src)
+	}
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// Value implements driver.Valuer. Null and missing values are stored as NULL
+func (n NullString) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	return n.String, nil
+}
+
+// Scan implements sql.Scanner. NULL is scanned as a present but null value
+func (n *NullString) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*n = NullString{Present: true}
+	case []byte:
+		*n = NewNullString(string(v))
+	case string:
+		*n = NewNullString(v)
+	case int64:
+		*n = NewNullString(strconv.FormatInt(v, 10))
+	case float64:
+		*n = NewNullString(strconv.FormatFloat(v, 'f', -1, 64))
+	default:
+		return fmt.Errorf("Cannot scan %T into NullString", src)
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+package coincap
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDriver is an in-memory database/sql driver. Every statement executed with
+// arguments appends them as a row to the table named by the DSN and every query
+// returns all rows of the table
+type fakeDriver struct {
+	mu     sync.Mutex
+	tables map[string][][]driver.Value
+}
+
+var testDriver = &fakeDriver{tables: make(map[string][][]driver.Value)}
+
+func init() {
+	sql.Register("coincapfake", testDriver)
+}
+
+// reset empties the table name, left over from an earlier run of the same test
+func (d *fakeDriver) reset(name string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.tables, name)
+}
+
+// rows returns the rows stored in the table name
+func (d *fakeDriver) rows(name string) [][]driver.Value {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([][]driver.Value(nil), d.tables[name]...)
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	return &fakeConn{driver: d, table: name}, nil
+}
+
+type fakeConn struct {
+	driver *fakeDriver
+	table  string
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{conn: c}, nil
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+type fakeStmt struct {
+	conn *fakeConn
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	d := s.conn.driver
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.tables[s.conn.table] = append(d.tables[s.conn.table], append([]driver.Value(nil), args...))
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	d := s.conn.driver
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return &fakeRows{rows: d.tables[s.conn.table]}, nil
+}
+
+type fakeRows struct {
+	rows [][]driver.Value
+	next int
+}
+
+func (r *fakeRows) Columns() []string {
+	if len(r.rows) == 0 {
+		return nil
+	}
+	cols := make([]string, len(r.rows[0]))
+	for i := range cols {
+		cols[i] = fmt.Sprintf("c%d", i)
+	}
+	return cols
+}
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.next])
+	r.next++
+	return nil
+}
+
+func TestSQLRoundTrip(t *testing.T) {
+	testDriver.reset(t.Name())
+	db, err := sql.Open("coincapfake", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	type row struct {
+		period    Timestamp
+		price     Decimal
+		maxSupply NullString
+	}
+	rows := []row{
+		{Timestamp{fromMillis(1536336916333)}, MustParseDecimal("6379.3997635993342453"), NewNullString("21000000")},
+		{Timestamp{}, NullDecimal(), NullString{Present: true}},
+	}
+	for _, r := range rows {
+		if _, err := db.Exec("INSERT INTO history VALUES (?, ?, ?)", r.period, r.price, r.maxSupply); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the driver received plain values
+	stored := testDriver.rows(t.Name())
+	if len(stored) != len(rows) {
+		t.Fatalf("Expected %d rows to be stored but got %d", len(rows), len(stored))
+	}
+	if _, ok := stored[0][0].(time.Time); !ok {
+		t.Errorf("Expected the timestamp to be stored as time.Time but got %T", stored[0][0])
+	}
+	if stored[0][1] != "6379.3997635993342453" {
+		t.Errorf("Expected the exact decimal string to be stored but got %v", stored[0][1])
+	}
+	for i, v := range stored[1] {
+		if v != nil {
+			t.Errorf("Expected column %d to be stored as NULL but got %v", i, v)
+		}
+	}
+
+	result, err := db.Query("SELECT * FROM history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer result.Close()
+	i := 0
+	for ; result.Next(); i++ {
+		var got row
+		if err := result.Scan(&got.period, &got.price, &got.maxSupply); err != nil {
+			t.Fatal(err)
+		}
+		if i >= len(rows) {
+			t.Fatalf("Expected %d rows but got more", len(rows))
+		}
+		want := rows[i]
+		if !got.period.Equal(want.period.Time) || !got.price.Equal(want.price) || got.price.IsNull() != want.price.IsNull() || got.maxSupply != want.maxSupply {
+			t.Errorf("Row %d: expected %+v but got %+v", i, want, got)
+		}
+	}
+	if err := result.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if i != len(rows) {
+		t.Errorf("Expected %d rows but got %d", len(rows), i)
+	}
+}
+
+func TestTimestampScan(t *testing.T) {
+	ms := fromMillis(1536336916333)
+	tests := []struct {
+		src     interface{}
+		want    time.Time
+		wantErr bool
+	}{
+		{src: ms, want: ms},
+		{src: int64(1536336916333), want: ms},
+		{src: float64(1536336916333.5), want: ms.Add(500 * time.Microsecond)},
+		{src: []byte("1536336916333"), want: ms},
+		{src: "2018-09-07T16:15:16.333Z", want: ms},
+		{src: nil},
+		{src: "soon", wantErr: true},
+		{src: true, wantErr: true},
+	}
+	for _, test := range tests {
+		ts := Timestamp{time.Unix(1, 0)}
+		err := ts.Scan(test.src)
+		if (err != nil) != test.wantErr {
+			t.Errorf("Scan(%#v): unexpected error %v", test.src, err)
+			continue
+		}
+		if !test.wantErr && !ts.Equal(test.want) {
+			t.Errorf("Scan(%#v): expected %v but got %v", test.src, test.want, ts.Time)
+		}
+	}
+}
+
+func TestDecimalScan(t *testing.T) {
+	tests := []struct {
+		src     interface{}
+		want    string
+		null    bool
+		wantErr bool
+	}{
+		{src: []byte("6379.3997635993342453"), want: "6379.3997635993342453"},
+		{src: "-0.0000000001", want: "-0.0000000001"},
+		{src: int64(42), want: "42"},
+		{src: float64(0.25), want: "0.25"},
+		{src: nil, null: true},
+		{src: "abc", wantErr: true},
+		{src: time.Now(), wantErr: true},
+	}
+	for _, test := range tests {
+		var d Decimal
+		err := d.Scan(test.src)
+		if (err != nil) != test.wantErr {
+			t.Errorf("Scan(%#v): unexpected error %v", test.src, err)
+			continue
+		}
+		if test.wantErr {
+			continue
+		}
+		if d.IsNull() != test.null || d.String() != test.want {
+			t.Errorf("Scan(%#v): expected %q (null %v) but got %q (null %v)", test.src, test.want, test.null, d.String(), d.IsNull())
+		}
+	}
+}
+
+func TestNullStringScan(t *testing.T) {
+	tests := []struct {
+		src     interface{}
+		want    NullString
+		wantErr bool
+	}{
+		{src: "bitcoin", want: NewNullString("bitcoin")},
+		{src: []byte(""), want: NewNullString("")},
+		{src: int64(21000000), want: NewNullString("21000000")},
+		{src: nil, want: NullString{Present: true}},
+		{src: time.Now(), wantErr: true},
+	}
+	for _, test := range tests {
+		var n NullString
+		err := n.Scan(test.src)
+		if (err != nil) != test.wantErr {
+			t.Errorf("Scan(%#v): unexpected error %v", test.src, err)
+			continue
+		}
+		if !test.wantErr && n != test.want {
+			t.Errorf("Scan(%#v): expected %+v but got %+v", test.src, test.want, n)
+		}
+	}
+}
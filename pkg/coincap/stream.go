@@ -0,0 +1,294 @@
+package coincap
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// defaultStreamBuffer is the number of updates a stream buffers for its consumer by default
+const defaultStreamBuffer = 64
+
+// Backpressure decides what a stream does with new updates while its consumer is
+// falling behind and the buffer is full
+type Backpressure int
+
+const (
+	// BackpressureBlock waits for the consumer, which stops reading from the connection
+	// until there is room. Nothing is lost but a very slow consumer can get disconnected
+	BackpressureBlock Backpressure = iota
+	// BackpressureDropOldest discards the oldest buffered update to make room for the new one
+	BackpressureDropOldest
+	// BackpressureDropNewest discards the new update, keeping what is buffered
+	BackpressureDropNewest
+)
+
+// StreamOptions configures how a realtime stream delivers updates
+type StreamOptions struct {
+	BufferSize   int          // updates buffered for the consumer. Defaults to 64
+	Backpressure Backpressure // what to do when the buffer is full. Defaults to BackpressureBlock
+
+	// Reconnect makes the stream detect dead connections and reconnect rather than stop.
+	// Every reconnection is reported on Gaps. nil disables reconnecting
+	Reconnect *ReconnectPolicy
+}
+
+// errResubscribe ends a connection replaced by resubscribe
+var errResubscribe = errors.New("coincap: resubscribing")
+
+// gapBuffer is the number of gaps a stream buffers. Older gaps are discarded if they are not read
+const gapBuffer = 16
+
+// bufferSize returns the buffer size to use for opts, which may be nil
+func (opts *StreamOptions) bufferSize() int {
+	if opts == nil || opts.BufferSize <= 0 {
+		return defaultStreamBuffer
+	}
+	return opts.BufferSize
+}
+
+// backpressure returns the policy to use for opts, which may be nil
+func (opts *StreamOptions) backpressure() Backpressure {
+	if opts == nil {
+		return BackpressureBlock
+	}
+	return opts.Backpressure
+}
+
+// stream holds the connection handling and lifecycle shared by the realtime streams
+type stream struct {
+	// dropped and malformed are accessed atomically. 64-bit atomic counters go first
+	// in their struct so they stay 64-bit aligned on 32-bit platforms
+	dropped   uint64
+	malformed uint64 // messages skipped because they could not be decoded
+
+	client    *Client
+	target    func() (string, url.Values) // path and query of the feed, asked again on every reconnection
+	reconnect *ReconnectPolicy
+	clock     clock
+	policy    Backpressure
+	cancel    context.CancelFunc
+	done      chan struct{}
+	gaps      chan StreamGap
+	refresh   chan struct{} // asks for the connection to be replaced after target changed
+
+	mu  sync.Mutex
+	err error
+}
+
+// openStream connects to the feed named by target and returns a stream for it,
+// which is stopped by cancelling the returned context
+func (c *Client) openStream(ctx context.Context, opts *StreamOptions, target func() (string, url.Values)) (context.Context, *stream, *websocket.Conn, error) {
+	path, query := target()
+	conn, err := c.dialWebsocket(ctx, path, query)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s := &stream{
+		client:  c,
+		target:  target,
+		clock:   c.clock,
+		policy:  opts.backpressure(),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		gaps:    make(chan StreamGap, gapBuffer),
+		refresh: make(chan struct{}, 1),
+	}
+	if opts != nil {
+		s.reconnect = opts.Reconnect
+	}
+	return ctx, s, conn, nil
+}
+
+// run passes every message received to handle along with the time it was received, until
+// ctx is done, handle returns an error other than a DecodeError or the connection fails and cannot be reestablished.
+// Failures other than ctx ending are recorded as the stream's error
+func (s *stream) run(ctx context.Context, conn *websocket.Conn, handle func(msg []byte, received time.Time) error) {
+	defer close(s.gaps)
+	for {
+		fatal, err := s.readLoop(ctx, conn, handle)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == errResubscribe {
+			// switch straight to a connection for the new target. Updates published
+			// while switching are missed, so the switch is reported like a reconnection
+			switchedAt := s.clock.Now()
+			path, query := s.target()
+			if conn, err = s.client.dialWebsocket(ctx, path, query); err == nil {
+				s.addGap(StreamGap{DisconnectedAt: switchedAt, ReconnectedAt: s.clock.Now(), Attempts: 1, Err: errResubscribe})
+				continue
+			}
+			if ctx.Err() != nil {
+				return
+			}
+		}
+		if fatal || s.reconnect == nil {
+			s.setErr(err)
+			return
+		}
+
+		disconnectedAt, lostErr := s.clock.Now(), err
+		s.client.logf("coincap: Lost connection to realtime feed: %v, reconnecting", err)
+		var attempts int
+		conn, attempts, err = s.redial(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				s.setErr(err)
+			}
+			return
+		}
+		s.addGap(StreamGap{DisconnectedAt: disconnectedAt, ReconnectedAt: s.clock.Now(), Attempts: attempts, Err: lostErr})
+	}
+}
+
+// readLoop reads messages from conn and passes them to handle until ctx is done or either fails.
+// Messages handle cannot decode are logged and skipped. fatal is set if handle failed otherwise,
+// in which case reconnecting would not help.
+// conn is closed when readLoop returns
+func (s *stream) readLoop(ctx context.Context, conn *websocket.Conn, handle func(msg []byte, received time.Time) error) (fatal bool, err error) {
+	// closing the connection unblocks the read below once ctx is done
+	stopped, refreshed := make(chan struct{}), make(chan struct{})
+	defer close(stopped)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stopped:
+		case <-s.refresh:
+			close(refreshed)
+		}
+		closeWebsocket(conn)
+	}()
+	s.keepAlive(conn, stopped)
+
+	for {
+		s.extendDeadline(conn)
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			select {
+			case <-refreshed:
+				return false, errResubscribe
+			default:
+			}
+			return false, err
+		}
+		if err := handle(msg, s.clock.Now()); err != nil {
+			var decodeErr *DecodeError
+			if !errors.As(err, &decodeErr) {
+				return true, err
+			}
+			// one bad message says nothing about the ones that follow
+			atomic.AddUint64(&s.malformed, 1)
+			s.client.logf("coincap: Skipping malformed message: %v", err)
+		}
+	}
+}
+
+// resubscribe replaces the connection with one to the current target, e.g. after the
+// subscribed assets changed. The switch is reported on Gaps since updates published
+// while switching connections are missed
+func (s *stream) resubscribe() {
+	select {
+	case s.refresh <- struct{}{}:
+	default:
+		// a refresh is already pending and will pick up the latest target
+	}
+}
+
+// addGap reports a reconnection, discarding the oldest gap if nobody is reading them
+func (s *stream) addGap(gap StreamGap) {
+	offerGap(s.gaps, gap)
+}
+
+// offerGap sends gap on the buffered channel gaps, discarding the oldest gap if it is full
+func offerGap(gaps chan StreamGap, gap StreamGap) {
+	for {
+		select {
+		case gaps <- gap:
+			return
+		default:
+		}
+		select {
+		case <-gaps:
+		default:
+		}
+	}
+}
+
+// Gaps returns the channel reconnections and resubscriptions are reported on. Updates may have
+// been missed during every gap. The channel is buffered and closed when the stream stops
+func (s *stream) Gaps() <-chan StreamGap {
+	return s.gaps
+}
+
+// offer hands an update to the consumer according to the stream's backpressure policy.
+// It returns false if send gave up waiting for the consumer
+func (s *stream) offer(send func(wait bool) bool, evict func() bool) bool {
+	return applyBackpressure(s.policy, s.drop, send, evict)
+}
+
+// applyBackpressure hands an update to a consumer according to policy, calling drop for every
+// update discarded. send puts the update in the consumer's buffer, waiting for room if wait is
+// set, and reports whether it did. evict discards the oldest buffered update if there is one.
+// It returns false if send gave up waiting
+func applyBackpressure(policy Backpressure, drop func(), send func(wait bool) bool, evict func() bool) bool {
+	switch policy {
+	case BackpressureDropNewest:
+		if !send(false) {
+			drop()
+		}
+		return true
+	case BackpressureDropOldest:
+		for !send(false) {
+			if evict() {
+				drop()
+			}
+		}
+		return true
+	default:
+		return send(true)
+	}
+}
+
+// drop counts an update discarded because the consumer fell behind
+func (s *stream) drop() {
+	atomic.AddUint64(&s.dropped, 1)
+}
+
+func (s *stream) setErr(err error) {
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+}
+
+// Err returns the error that stopped the stream, or nil if it is still running or
+// was stopped by Close or its context
+func (s *stream) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Dropped returns the number of updates discarded because the consumer fell behind
+func (s *stream) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Malformed returns the number of messages skipped because they could not be decoded
+func (s *stream) Malformed() uint64 {
+	return atomic.LoadUint64(&s.malformed)
+}
+
+// Close stops the stream and closes the connection. It waits for the stream to stop
+func (s *stream) Close() error {
+	s.cancel()
+	<-s.done
+	return nil
+}
@@ -1,36 +1,121 @@
 package coincap
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
 	"strconv"
+	"strings"
 	"time"
 )
 
 // Timestamp is wrapper around time.Time with custom marshaling behaviour
-// specific to the format returned by the CoinCap API
+// specific to the format returned by the CoinCap API.
+// CoinCap timestamps are unix milliseconds. The zero Timestamp stands for a missing or null
+// timestamp and is distinct from the unix epoch
 type Timestamp struct {
 	time.Time
 }
 
+// toMillis returns t in unix milliseconds without overflowing for dates far from 1970
+func toMillis(t time.Time) int64 {
+	return t.Unix()*1e3 + int64(t.Nanosecond())/1e6
+}
+
+// fromMillis returns the time ms unix milliseconds after the epoch
+func fromMillis(ms int64) time.Time {
+	sec, rem := ms/1e3, ms%1e3
+	return time.Unix(sec, rem*1e6)
+}
+
+// parseMillis parses unix milliseconds given as an integer such as "1536336916333",
+// a fraction such as "1536336916333.25" or in exponent notation
+func parseMillis(s string) (time.Time, error) {
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return fromMillis(ms), nil
+	}
+
+	// split fractions exactly into whole milliseconds and nanoseconds
+	if i := strings.IndexByte(s, '.'); i >= 0 && !strings.ContainsAny(s, "eE") {
+		whole, frac := s[:i], s[i+1:]
+		if whole == "" || whole == "-" || whole == "+" {
+			whole += "0"
+		}
+		ms, err := strconv.ParseInt(whole, 10, 64)
+		if err == nil && frac != "" && strings.Trim(frac, "0123456789") == "" {
+			// only the first 6 digits fit in nanoseconds
+			if len(frac) > 6 {
+				frac = frac[:6]
+			}
+			ns, _ := strconv.ParseInt(frac+strings.Repeat("0", 6-len(frac)), 10, 64)
+			if strings.HasPrefix(whole, "-") {
+				ns = -ns
+			}
+			return fromMillis(ms).Add(time.Duration(ns)), nil
+		}
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) || math.Abs(f) > math.MaxInt64/2 {
+		return time.Time{}, fmt.Errorf("Invalid timestamp %q", s)
+	}
+	whole := math.Trunc(f)
+	return fromMillis(int64(whole)).Add(time.Duration((f - whole) * 1e6)), nil
+}
+
 // UnmarshalJSON implements json.Unmarshaler
-// Custom unmarshaller to handle that the timestamp is not in a standard format
+// Custom unmarshaller to handle that the timestamp is not in a standard format.
+// Integer, fractional and quoted milliseconds are accepted. null and "" leave the Timestamp zero
 func (t *Timestamp) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if bytes.Equal(b, []byte("null")) {
+		t.Time = time.Time{}
+		return nil
+	}
+
+	s := string(b)
+	if len(b) > 0 && b[0] == '"' {
+		if err := json.Unmarshal(b, &s); err != nil {
+			return err
+		}
+	}
+	return t.UnmarshalText([]byte(s))
+}
 
-	// CoinCap timestamp is unix milliseconds
-	m, err := strconv.ParseInt(string(b), 10, 64)
+// MarshalJSON implements json.Marshaler. The zero Timestamp is encoded as null
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+	return []byte(t.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler and accepts the same milliseconds as UnmarshalJSON.
+// Empty text leaves the Timestamp zero
+func (t *Timestamp) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	if s == "" {
+		t.Time = time.Time{}
+		return nil
+	}
+	parsed, err := parseMillis(s)
 	if err != nil {
 		return err
 	}
-	// Convert from milliseconds to nanoseconds
-	t.Time = time.Unix(0, m*1e6)
+	t.Time = parsed
 	return nil
 }
 
-// MarshalJSON implements json.Marshaler
-func (t *Timestamp) MarshalJSON() ([]byte, error) {
-	return []byte(strconv.Itoa(int(t.UnixNano() / 1e6))), nil // convert to milliseconds
+// MarshalText implements encoding.TextMarshaler. The zero Timestamp is encoded as empty text
+func (t Timestamp) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
 }
 
-// String implements stringer
-func (t *Timestamp) String() string {
-	return strconv.Itoa(int(t.UnixNano() / 1e6))
+// String implements stringer and returns unix milliseconds, or an empty string for the zero Timestamp
+func (t Timestamp) String() string {
+	if t.IsZero() {
+		return ""
+	}
+	return strconv.FormatInt(toMillis(t.Time), 10)
 }
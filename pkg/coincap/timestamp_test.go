@@ -0,0 +1,140 @@
+package coincap
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestTimestampUnmarshalJSON(t *testing.T) {
+	ms := func(v int64) time.Time { return fromMillis(v) }
+
+	tests := []struct {
+		name    string
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "integer", in: `1536336916333`, want: ms(1536336916333)},
+		{name: "quoted integer", in: `"1536336916333"`, want: ms(1536336916333)},
+		{name: "fraction", in: `1536336916333.5`, want: ms(1536336916333).Add(500 * time.Microsecond)},
+		{name: "quoted fraction", in: `"1536336916333.123456789"`, want: ms(1536336916333).Add(123456 * time.Nanosecond)},
+		{name: "exponent", in: `1.536336916333e12`, want: ms(1536336916333)},
+		{name: "epoch", in: `0`, want: time.Unix(0, 0)},
+		{name: "negative", in: `-1500`, want: time.Unix(-1, -5e8)},
+		{name: "beyond nanosecond range", in: `3623437394831867904`, want: ms(3623437394831867904)},
+		{name: "whitespace", in: ` 1536336916333 `, want: ms(1536336916333)},
+		{name: "null", in: `null`},
+		{name: "empty string", in: `""`},
+		{name: "garbage", in: `"yesterday"`, wantErr: true},
+		{name: "bool", in: `true`, wantErr: true},
+		{name: "object", in: `{}`, wantErr: true},
+	}
+	for _, test := range tests {
+		// start from a non-zero value to make sure null resets it
+		ts := Timestamp{time.Unix(1, 0)}
+		err := json.Unmarshal([]byte(test.in), &ts)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+		if !test.wantErr && !ts.Equal(test.want) {
+			t.Errorf("%s: expected %v but got %v", test.name, test.want, ts.Time)
+		}
+	}
+}
+
+func TestTimestampMarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		in   Timestamp
+		want string
+	}{
+		{"milliseconds", Timestamp{fromMillis(1536336916333)}, `1536336916333`},
+		{"sub-millisecond truncated", Timestamp{fromMillis(1536336916333).Add(999 * time.Microsecond)}, `1536336916333`},
+		{"epoch", Timestamp{time.Unix(0, 0)}, `0`},
+		{"before epoch", Timestamp{time.Unix(-1, 0)}, `-1000`},
+		{"far future", Timestamp{fromMillis(3623437394831867904)}, `3623437394831867904`},
+		{"zero", Timestamp{}, `null`},
+	}
+	for _, test := range tests {
+		// marshal both by value and through a pointer
+		for _, v := range []interface{}{test.in, &test.in} {
+			b, err := json.Marshal(v)
+			if err != nil {
+				t.Errorf("%s: %v", test.name, err)
+				continue
+			}
+			if string(b) != test.want {
+				t.Errorf("%s: expected %s but got %s", test.name, test.want, b)
+			}
+		}
+	}
+}
+
+func TestTimestampByValueInStructs(t *testing.T) {
+	candle := Candle{Open: "1", Period: Timestamp{fromMillis(1536243000000)}}
+	b, err := json.Marshal(candle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out Candle
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !out.Period.Equal(candle.Period.Time) {
+		t.Errorf("Expected period %v to round trip but got %v from %s", candle.Period, out.Period, b)
+	}
+
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["period"] != float64(1536243000000) {
+		t.Errorf("Expected period to be marshaled as milliseconds but got %v", m["period"])
+	}
+}
+
+func TestTimestampText(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    Timestamp
+		wantErr bool
+	}{
+		{name: "milliseconds", in: "1536336916333", want: Timestamp{fromMillis(1536336916333)}},
+		{name: "fraction", in: "1536336916333.5", want: Timestamp{fromMillis(1536336916333).Add(500 * time.Microsecond)}},
+		{name: "empty", in: ""},
+		{name: "invalid", in: "now", wantErr: true},
+	}
+	for _, test := range tests {
+		var ts Timestamp
+		err := ts.UnmarshalText([]byte(test.in))
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+		if !ts.Equal(test.want.Time) {
+			t.Errorf("%s: expected %v but got %v", test.name, test.want, ts.Time)
+		}
+	}
+
+	text, err := Timestamp{fromMillis(1536336916333)}.MarshalText()
+	if err != nil || string(text) != "1536336916333" {
+		t.Errorf("Expected text 1536336916333 but got %q (%v)", text, err)
+	}
+	if text, _ := (Timestamp{}).MarshalText(); len(text) != 0 {
+		t.Errorf("Expected the zero timestamp to marshal to empty text but got %q", text)
+	}
+}
+
+func TestTimestampString(t *testing.T) {
+	if s := (Timestamp{}).String(); s != "" {
+		t.Errorf("Expected the zero timestamp to be empty but got %q", s)
+	}
+	ts := &Timestamp{fromMillis(1536336916333)}
+	if s := ts.String(); s != "1536336916333" {
+		t.Errorf("Expected 1536336916333 but got %q", s)
+	}
+}
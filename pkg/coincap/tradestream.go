@@ -0,0 +1,123 @@
+package coincap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// ErrNoTradeSocket is returned when subscribing to the trades of an exchange that
+// CoinCap does not publish a trade socket for
+var ErrNoTradeSocket = errors.New("coincap: exchange has no trade socket")
+
+// Trade is a single trade pushed by the trades feed of an exchange.
+// Unlike the REST endpoints the feed sends numbers rather than strings,
+// so they are decoded straight into exact Decimals
+type Trade struct {
+	Exchange   string    `json:"exchange"`  // unique identifier for the exchange
+	Base       string    `json:"base"`      // unique identifier for the asset bought or sold
+	Quote      string    `json:"quote"`     // unique identifier for the asset used to pay
+	Direction  string    `json:"direction"` // "buy" or "sell"
+	Price      Decimal   `json:"price"`     // price of one unit of base in quote
+	Volume     Decimal   `json:"volume"`    // amount of base traded
+	PriceUsd   Decimal   `json:"priceUsd"`  // price of one unit of base in USD
+	Timestamp  Timestamp `json:"timestamp"` // time of the trade reported by the exchange
+	ReceivedAt time.Time `json:"-"`         // time the trade was received
+}
+
+// TradeStream delivers realtime trades from the wss://ws.coincap.io/trades/{exchange} feed.
+// It is used like PriceStream
+type TradeStream struct {
+	*stream
+	trades chan Trade
+}
+
+// TradeStream connects to the trades feed of an exchange. CoinCap only publishes trades
+// for exchanges whose Socket field is true, ExchangeTradeStream checks this first.
+// The stream runs until ctx is done, Close is called or the connection fails
+func (c *Client) TradeStream(ctx context.Context, exchangeID string, opts *StreamOptions) (*TradeStream, error) {
+	if exchangeID == "" {
+		return nil, fmt.Errorf("ExchangeID is required")
+	}
+
+	ctx, base, conn, err := c.openStream(ctx, opts, func() (string, url.Values) {
+		return "/trades/" + url.PathEscape(exchangeID), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s := &TradeStream{
+		stream: base,
+		trades: make(chan Trade, opts.bufferSize()),
+	}
+	go func() {
+		defer close(s.done)
+		defer close(s.trades)
+		s.run(ctx, conn, func(msg []byte, received time.Time) error {
+			return s.handle(ctx, msg, received)
+		})
+	}()
+	return s, nil
+}
+
+// ExchangeTradeStream looks up the exchange and connects to its trades feed.
+// An error matching ErrNoTradeSocket is returned if the exchange does not have one
+func (c *Client) ExchangeTradeStream(ctx context.Context, exchangeID string, opts *StreamOptions) (*TradeStream, error) {
+	exchange, _, err := c.ExchangeByIDWithContext(ctx, exchangeID)
+	if err != nil {
+		return nil, err
+	}
+	if !exchange.Socket {
+		return nil, fmt.Errorf("Cannot stream trades from %s: %w", exchangeID, ErrNoTradeSocket)
+	}
+	return c.TradeStream(ctx, exchangeID, opts)
+}
+
+// handle decodes a message of the trades feed and delivers the trade
+func (s *TradeStream) handle(ctx context.Context, msg []byte, received time.Time) error {
+	var trade Trade
+	if err := json.Unmarshal(msg, &trade); err != nil {
+		return newDecodeError("/trades/{exchange}", err)
+	}
+	trade.ReceivedAt = received
+	if !s.deliver(ctx, trade) {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// deliver hands t to the consumer according to the stream's backpressure policy.
+// It returns false if ctx ended first
+func (s *TradeStream) deliver(ctx context.Context, t Trade) bool {
+	return s.offer(func(wait bool) bool {
+		if !wait {
+			select {
+			case s.trades <- t:
+				return true
+			default:
+				return false
+			}
+		}
+		select {
+		case s.trades <- t:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}, func() bool {
+		select {
+		case <-s.trades:
+			return true
+		default:
+			return false
+		}
+	})
+}
+
+// Trades returns the channel trades are delivered on. The channel is closed when the stream stops
+func (s *TradeStream) Trades() <-chan Trade {
+	return s.trades
+}
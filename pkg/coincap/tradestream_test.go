@@ -0,0 +1,172 @@
+package coincap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const tradeMessage = `{"exchange":"binance","base":"bitcoin","quote":"tether","direction":"buy","price":8093.67,"volume":0.0148,"timestamp":1562876355455,"priceUsd":8095.9034829}`
+
+// tradeN returns a trade message with the given volume to tell trades apart
+func tradeN(n int) []byte {
+	return []byte(fmt.Sprintf(`{"exchange":"binance","base":"bitcoin","quote":"tether","direction":"sell","price":1,"volume":%d,"timestamp":1562876355455,"priceUsd":1}`, n))
+}
+
+// receiveTrade returns the next trade or fails the test after a second
+func receiveTrade(t *testing.T, trades <-chan Trade) Trade {
+	t.Helper()
+	select {
+	case trade, ok := <-trades:
+		if !ok {
+			t.Fatal("Expected a trade but the stream stopped")
+		}
+		return trade
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for a trade")
+	}
+	return Trade{}
+}
+
+// waitFor polls cond for up to a second
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestTradeStream(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	serveWebsocket("/trades/binance", func(conn *websocket.Conn, req *http.Request) {
+		conn.WriteMessage(websocket.TextMessage, []byte(tradeMessage))
+		drain(conn)
+	})
+
+	stream, err := client.TradeStream(context.Background(), "binance", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	trade := receiveTrade(t, stream.Trades())
+	if trade.Exchange != "binance" || trade.Base != "bitcoin" || trade.Quote != "tether" || trade.Direction != "buy" {
+		t.Errorf("Unexpected trade %+v", trade)
+	}
+	if trade.Price.String() != "8093.67" || trade.Volume.String() != "0.0148" || trade.PriceUsd.String() != "8095.9034829" {
+		t.Errorf("Expected exact prices but got %s, %s and %s", trade.Price, trade.Volume, trade.PriceUsd)
+	}
+	if trade.Timestamp.String() != "1562876355455" || trade.ReceivedAt.IsZero() {
+		t.Errorf("Expected the trade and receive times to be set but got %s and %v", trade.Timestamp, trade.ReceivedAt)
+	}
+}
+
+func TestTradeStreamBackpressure(t *testing.T) {
+	tests := []struct {
+		policy  Backpressure
+		want    []string
+		dropped uint64
+	}{
+		{BackpressureBlock, []string{"1", "2", "3", "4", "5"}, 0},
+		{BackpressureDropNewest, []string{"1", "2"}, 3},
+		{BackpressureDropOldest, []string{"4", "5"}, 3},
+	}
+	for _, test := range tests {
+		teardown := setup()
+
+		sent := make(chan struct{})
+		serveWebsocket("/trades/binance", func(conn *websocket.Conn, req *http.Request) {
+			for i := 1; i <= 5; i++ {
+				conn.WriteMessage(websocket.TextMessage, tradeN(i))
+			}
+			close(sent)
+			drain(conn)
+		})
+
+		stream, err := client.TradeStream(context.Background(), "binance", &StreamOptions{BufferSize: 2, Backpressure: test.policy})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// let the consumer fall behind until every trade has been handled
+		<-sent
+		if test.policy != BackpressureBlock {
+			waitFor(t, "trades to be dropped", func() bool { return stream.Dropped() == test.dropped })
+		}
+
+		for _, want := range test.want {
+			if trade := receiveTrade(t, stream.Trades()); trade.Volume.String() != want {
+				t.Errorf("Policy %d: expected trade %s but got %s", test.policy, want, trade.Volume)
+			}
+		}
+		if stream.Dropped() != test.dropped {
+			t.Errorf("Policy %d: expected %d dropped trades but got %d", test.policy, test.dropped, stream.Dropped())
+		}
+		stream.Close()
+		teardown()
+	}
+}
+
+func TestTradeStreamMalformed(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	serveWebsocket("/trades/binance", func(conn *websocket.Conn, req *http.Request) {
+		conn.WriteMessage(websocket.TextMessage, []byte(`{"exchange":"binance","price":"abc"}`))
+		conn.WriteMessage(websocket.TextMessage, tradeN(1))
+		drain(conn)
+	})
+
+	stream, err := client.TradeStream(context.Background(), "binance", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+	if trade := receiveTrade(t, stream.Trades()); trade.Exchange != "binance" {
+		t.Errorf("Expected the malformed message to be skipped but got %+v", trade)
+	}
+	if stream.Malformed() != 1 || stream.Err() != nil {
+		t.Errorf("Expected one malformed message and no error but got %d and %v", stream.Malformed(), stream.Err())
+	}
+}
+
+func TestExchangeTradeStream(t *testing.T) {
+	teardown := setup()
+	defer teardown()
+
+	r.HandleFunc("/exchanges/{id}", func(w http.ResponseWriter, req *http.Request) {
+		socket := req.URL.Path == "/exchanges/gdax"
+		fmt.Fprintf(w, `{"data":{"id":"x","socket":%v},"timestamp":1536336916333}`, socket)
+	})
+	serveWebsocket("/trades/gdax", func(conn *websocket.Conn, req *http.Request) {
+		drain(conn)
+	})
+	r.HandleFunc("/trades/kraken", func(w http.ResponseWriter, req *http.Request) {
+		t.Errorf("Expected no connection to an exchange without a socket")
+	})
+
+	if _, err := client.ExchangeTradeStream(context.Background(), "kraken", nil); !errors.Is(err, ErrNoTradeSocket) {
+		t.Errorf("Expected ErrNoTradeSocket but got %v", err)
+	}
+
+	stream, err := client.ExchangeTradeStream(context.Background(), "gdax", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.Close()
+
+	if _, err := client.TradeStream(context.Background(), "", nil); err == nil {
+		t.Errorf("Expected an error without an exchange")
+	}
+}
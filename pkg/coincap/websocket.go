@@ -0,0 +1,93 @@
+package coincap
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// defaultHandshakeTimeout bounds the websocket handshake when the client has no timeout
+const defaultHandshakeTimeout = 30 * time.Second
+
+// websocketBase returns the base URL of the realtime feeds. Unless set with WithWebsocketURL
+// it is derived from the base URL: http becomes ws, https becomes wss and an "api." host
+// becomes "ws.", so https://api.coincap.io/v2 is served by wss://ws.coincap.io
+func (c *Client) websocketBase() (*url.URL, error) {
+	if c.websocketURL != "" {
+		return url.Parse(c.websocketURL)
+	}
+
+	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "https":
+		u.Scheme = "wss"
+	case "http":
+		u.Scheme = "ws"
+	case "ws", "wss":
+	default:
+		return nil, fmt.Errorf("Cannot derive a websocket URL from %q, use WithWebsocketURL", c.baseURL)
+	}
+	if strings.HasPrefix(u.Host, "api.") {
+		u.Host = "ws." + strings.TrimPrefix(u.Host, "api.")
+	}
+	// the feeds are served from the root rather than below the api version
+	u.Path, u.RawPath, u.RawQuery = "", "", ""
+	return u, nil
+}
+
+// dialWebsocket connects to the feed at path below the websocket base URL
+func (c *Client) dialWebsocket(ctx context.Context, path string, query url.Values) (*websocket.Conn, error) {
+	base, err := c.websocketBase()
+	if err != nil {
+		return nil, err
+	}
+	u := *base
+	u.Path = strings.TrimSuffix(base.Path, "/") + path
+	u.RawQuery = query.Encode()
+
+	header := http.Header{}
+	if c.apiKey != "" {
+		header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	if c.userAgent != "" {
+		header.Set("User-Agent", c.userAgent)
+	}
+	dialer := websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: defaultHandshakeTimeout,
+	}
+	if c.timeout > 0 {
+		dialer.HandshakeTimeout = c.timeout
+	}
+
+	conn, resp, err := dialer.DialContext(ctx, u.String(), header)
+	if err != nil {
+		// report refused handshakes like any other failed api call
+		if resp != nil && resp.StatusCode != http.StatusSwitchingProtocols {
+			body, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+			apiErr := newAPIError(resp, body)
+			apiErr.URL = u.String()
+			return nil, c.redactAPIError(apiErr)
+		}
+		return nil, c.redactError(err)
+	}
+	c.logf("coincap: Connected to %s", u.String())
+	return conn, nil
+}
+
+// closeWebsocket politely closes conn, giving the server a moment to acknowledge
+func closeWebsocket(conn *websocket.Conn) {
+	msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+	conn.Close()
+}
@@ -0,0 +1,29 @@
+package coincap
+
+import "testing"
+
+func TestWebsocketBase(t *testing.T) {
+	tests := []struct {
+		opts    []Option
+		want    string
+		wantErr bool
+	}{
+		{opts: nil, want: "wss://ws.coincap.io"},
+		{opts: []Option{WithBaseURL("http://api.example.com/v2/")}, want: "ws://ws.example.com"},
+		{opts: []Option{WithBaseURL("http://127.0.0.1:8080")}, want: "ws://127.0.0.1:8080"},
+		{opts: []Option{WithBaseURL("https://proxy.internal/coincap")}, want: "wss://proxy.internal"},
+		{opts: []Option{WithBaseURL("https://api.coincap.io/v2"), WithWebsocketURL("wss://feeds.example.com/cc")}, want: "wss://feeds.example.com/cc"},
+		{opts: []Option{WithBaseURL("ftp://api.coincap.io")}, wantErr: true},
+	}
+	for _, test := range tests {
+		c := New(test.opts...)
+		u, err := c.websocketBase()
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: unexpected error %v", c.baseURL, err)
+			continue
+		}
+		if !test.wantErr && u.String() != test.want {
+			t.Errorf("%s: expected %s but got %s", c.baseURL, test.want, u)
+		}
+	}
+}